@@ -0,0 +1,352 @@
+// Package console plays games of Reversi in a terminal. It is shared by the reversi and
+// reversiSimulation commands.
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// A game between two players, each of which may be a person or an AI
+type Game struct {
+	*engine.Reversi
+	Players           map[int]engine.Player
+	Names             map[int]string
+	Clock             *engine.Clock
+	playOutsPerSecond []float64
+	mctTime           []float64
+	// Time left on the clock of each color after each number of moves played, so that undoing and
+	// redoing moves sets the clocks back to their times
+	clockTimes []map[int]time.Duration
+	// Rates the positions of a person who asks for a hint
+	Hints engine.Analyzer
+}
+
+// Number the seed of the hints is derived with, apart from the colors' numbers
+const hintsSeed int64 = 2
+
+// Initialize and return a new game between the given players, starting from the given game.
+// Hints are given by a player of their own, so that they don't change the random numbers or the table of
+// the computer players, and a seeded game plays the same with or without hints. It is created like the
+// computer player if there is one that can rate positions, otherwise it is MCTS, with the given
+// configuration and a seed derived from its seed.
+func NewGame(players map[int]engine.Player, names map[int]string, config engine.PlayerConfig, timeControl engine.TimeControl, start *engine.Reversi) *Game {
+	game := &Game{
+		Reversi: start,
+		Players: players,
+		Names:   names,
+		Clock:   engine.NewClock(timeControl),
+	}
+
+	config.Seed = engine.DeriveSeed(config.Seed, hintsSeed)
+	spec := "mcts"
+	for _, color := range []int{engine.Blue, engine.Red} {
+		if _, ok := players[color].(engine.Analyzer); ok {
+			spec = names[color]
+			break
+		}
+	}
+	// The spec already made a player of the same kind, so it makes an analyzer again
+	hints, err := engine.NewPlayer(spec, config)
+	if err != nil {
+		hints, _ = engine.NewPlayer("mcts", config)
+	}
+	game.Hints = hints.(engine.Analyzer)
+	game.recordClock()
+
+	return game
+}
+
+// Create the player with the given name: "human" for a person at the terminal, or any engine player
+func NewPlayer(name string, config engine.PlayerConfig) (engine.Player, error) {
+	if name == "human" {
+		return new(Human), nil
+	}
+
+	player, err := engine.NewPlayer(name, config)
+	if errors.Is(err, engine.ErrUnknownPlayer) {
+		playerName, _ := engine.SplitPlayerSpec(name)
+		return nil, fmt.Errorf("unknown player %q, expected one of %v", playerName, PlayerNames())
+	}
+	return player, err
+}
+
+// Get the names of the players that NewPlayer can create
+func PlayerNames() []string {
+	return append([]string{"human"}, engine.PlayerNames()...)
+}
+
+// Get the name of a color, color-coded
+func ColorName(color int) string {
+	if color == engine.Blue {
+		return "\033[94mBlue\033[0m"
+	}
+	return "\033[91mRed\033[0m"
+}
+
+// Return whether a person plays the given color
+func (r *Game) isHuman(color int) bool {
+	_, ok := r.Players[color].(*Human)
+	return ok
+}
+
+// Return whether a person plays the current turn
+func (r *Game) isHumanTurn() bool {
+	return r.isHuman(r.Turn())
+}
+
+// Get the display string for a chip
+func (r *Game) getDisplayChar(ind, code int, moves engine.Bitboard, labels map[int]string) string {
+	// If the position is empty (coded 0)
+	if code == engine.Empty {
+		// If the position was rated by a hint, display its rating instead
+		if label, ok := labels[ind]; ok {
+			return "\033[92m" + label + "\033[0m"
+		}
+		// If it's a person's turn, display color-code their valid next positions by name
+		if moves.Has(ind) {
+			return "\033[92m" + r.PosName(ind) + "\033[0m"
+		}
+		return ""
+	} else if code == engine.Red {
+		// Circle icon unicode is \u2B24
+		// Color-code red
+		return "\033[91m" + "\u2B24 " + "\033[0m"
+	} else if code == engine.Blue {
+		// Color-code blue
+		return "\033[94m" + "\u2B24 " + "\033[0m"
+	} else {
+		panic("Unknown display code given")
+	}
+}
+
+// Display the game board
+func (r *Game) Display() {
+
+	r.displayBoard(nil)
+
+	// Display score
+	fmt.Printf("\n\nBlue score:\t\033[94m%v\033[0m", r.Score(engine.Blue))
+	fmt.Printf("\nRed score:\t\033[91m%v\033[0m\n\n", r.Score(engine.Red))
+
+	// Display the time left on the game clock
+	if r.Clock.HasClock() {
+		fmt.Printf("Blue clock:\t%v\n", r.Clock.Remaining(engine.Blue).Round(time.Millisecond))
+		fmt.Printf("Red clock:\t%v\n\n", r.Clock.Remaining(engine.Red).Round(time.Millisecond))
+	}
+}
+
+// Display the chips, with the column letters above and the row numbers on the left, and the given labels
+// in place of the names of empty positions
+func (r *Game) displayBoard(labels map[int]string) {
+
+	// Only show the valid positions to people
+	var moves engine.Bitboard
+	if r.isHumanTurn() {
+		moves = r.Moves()
+	}
+
+	// Display column letters
+	fmt.Print("\t")
+	for col := 0; col < r.Size(); col++ {
+		if col != 0 {
+			fmt.Print("|\t")
+		}
+		fmt.Printf("%c\t", 'a'+col)
+	}
+	// Separate the rows by a line as wide as the board, with columns two tabs wide
+	separator := "\n\t" + strings.Repeat("-", 16*r.Size()-10)
+	fmt.Println(separator)
+
+	// Display board
+	for i, elm := range r.Board() {
+		if i%r.Size() == 0 {
+			if i != 0 {
+				fmt.Println(separator)
+			}
+			fmt.Printf("%v\t%v\t", i/r.Size()+1, r.getDisplayChar(i, elm, moves, labels))
+		} else {
+			fmt.Printf("|\t%v\t", r.getDisplayChar(i, elm, moves, labels))
+		}
+	}
+}
+
+// Play the turn of the current player
+func (r *Game) PlayMove() {
+
+	if r.isHumanTurn() {
+		r.playHumanTurn()
+		return
+	}
+
+	fmt.Printf("%v (%v) thinking....", ColorName(r.Turn()), r.Names[r.Turn()])
+
+	// Get the move within the time the clock allows
+	startTime := time.Now()
+	pos, stats := r.Players[r.Turn()].BestMove(r.Reversi, r.Clock.Budget(r.Reversi))
+
+	// If the player has no moves to make, pass the turn
+	if pos == engine.NoMove {
+		fmt.Print("Skipping turn.")
+		r.Pass()
+		r.recordClock()
+		return
+	}
+
+	if stats.TimeLimitExceeded {
+		fmt.Print("\nMax amount of time exceeded. Making decision...\n")
+	}
+
+	if stats.Book {
+		fmt.Print("\nPlayed from the opening book.\n")
+	}
+
+	// If the move was found by alpha-beta, show how deep it looked
+	if stats.Depth > 0 {
+		fmt.Printf("\nSearched %v moves deep.\n", stats.Depth)
+	}
+
+	// If the endgame was solved, the outcome is known
+	if stats.Proven {
+		fmt.Printf("\nSolved the endgame: with perfect play %v ends with a chip difference of %+d.\n", ColorName(r.Turn()), stats.Score)
+	}
+
+	// Keep track of the average number of playouts per second, if any playouts were needed
+	if stats.Playouts > 0 {
+		r.playOutsPerSecond = append(r.playOutsPerSecond, stats.PlayOutsPerSecond())
+		r.mctTime = append(r.mctTime, stats.Elapsed.Seconds())
+	}
+
+	fmt.Printf("\n%v plays %v.", ColorName(r.Turn()), r.PosName(pos))
+
+	r.Clock.Spend(r.Turn(), time.Since(startTime))
+	r.Play(pos)
+	r.recordClock()
+}
+
+// Print the winner of a game that is over, and return it
+func (r *Game) PrintResult() int {
+	winResult := r.CheckWin(true)
+
+	// If winResult is blue
+	if winResult == engine.Blue {
+		fmt.Print("\033[94mBlue\033[0m has won.\n\n")
+		// If winResult is red
+	} else if winResult == engine.Red {
+		fmt.Print("\033[91mRed\033[0m has won.\n\n")
+		// If the game is a tie
+	} else if winResult == engine.Tie {
+		fmt.Print("\033[93mIt's a tie!\033[0m\n\n")
+	}
+
+	fmt.Printf("\nThe average number of playouts per second is: %v\n", r.getAvgPlayOutsPerSecond())
+	fmt.Printf("\nThe average MCT turn: %v\n", r.getAvgMctTime())
+
+	return winResult
+}
+
+func getListAvg(list []float64) float64 {
+	if len(list) == 0 {
+		return 0
+	}
+
+	sum := float64(0)
+
+	for _, avg := range list {
+		sum += avg
+	}
+
+	return sum / float64(len(list))
+}
+
+// Get the avg number of playouts
+func (r *Game) getAvgPlayOutsPerSecond() float64 {
+	return getListAvg(r.playOutsPerSecond)
+}
+
+// Get the avg time of MCT per turn
+func (r *Game) getAvgMctTime() float64 {
+	return getListAvg(r.mctTime)
+}
+
+// Reads the input a line at a time
+var stdin = bufio.NewReader(os.Stdin)
+
+// Read a line of input, trimmed
+func ReadLine(prompt string) string {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+
+	// Quit if there is no input left to read, since nothing could be answered anymore
+	if err == io.EOF && line == "" {
+		fmt.Print("\nEnd of input.\n")
+		os.Exit(1)
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// Read a line of input, trimmed and lowercased
+func ReadInput(prompt string) string {
+	return strings.ToLower(ReadLine(prompt))
+}
+
+// Return whether the standard input is a terminal, in which case there is a person to prompt
+func IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// The null device is a character device too, but nobody is there
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// Get the color named by the given input: "b" or "blue", "r" or "red"
+func ParseColor(input string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "b", "blue":
+		return engine.Blue, nil
+	case "r", "red":
+		return engine.Red, nil
+	}
+	return engine.Empty, fmt.Errorf("unknown color %q, expected red or blue", input)
+}
+
+// Get the opening book named by the given setting: "builtin" for the book of standard openings, "none"
+// for no book, or the names of book files. Several books are combined when separated by commas, such as
+// "builtin,learned.txt". Transcripts in the files are read on a board of the given size, and the built-in
+// book only has positions of the standard board.
+func LoadBook(setting string, size int) (*engine.Book, error) {
+	if setting == "none" || setting == "" {
+		return nil, nil
+	}
+
+	book := engine.NewBook()
+	for _, name := range strings.Split(setting, ",") {
+		if name == "builtin" {
+			book.Merge(engine.BuiltinBook())
+			continue
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = book.Read(f, size)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+	}
+	return book, nil
+}
@@ -0,0 +1,259 @@
+package console
+
+import (
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A person entering moves at the terminal
+type Human struct{}
+
+// Get the position entered as a coordinate such as "d3" or as a number from 0 (a1)
+func parsePosition(r *engine.Reversi, input string) (int, error) {
+	if pos, err := strconv.Atoi(input); err == nil {
+		return pos, nil
+	}
+	return r.ParsePos(input)
+}
+
+// Get the entered position and return whether it is valid for the current turn
+func parseValidPosition(r *engine.Reversi, input string) (int, bool) {
+
+	pos, err := parsePosition(r, input)
+
+	if err != nil {
+		fmt.Print("Failed to read the position, enter a coordinate such as d3.")
+		return engine.NoMove, false
+	}
+
+	return pos, r.IsValidPosition(pos)
+}
+
+// Ask for the next position until a valid one is entered, or return NoMove if there is none
+func (h *Human) BestMove(r *engine.Reversi, timeLimit time.Duration) (int, engine.Stats) {
+
+	// Get the valid positions for the player
+	positons := r.ValidPositions()
+
+	// If the player has no valid positions, pass the turn
+	if positons == nil {
+		return engine.NoMove, engine.Stats{}
+	}
+
+	// Get next player position
+	nextPos := ReadLine("\nPlease enter your next position: ")
+
+	// If the entered position is invalid
+	p, ok := parseValidPosition(r, nextPos)
+	for !ok {
+		nextPos = ReadLine("\nInvalid position entered. Please enter your next position: ")
+		p, ok = parseValidPosition(r, nextPos)
+	}
+
+	return p, engine.Stats{}
+}
+
+// Play the turn of a person, who may also enter commands:
+// save <file>: save the game to a file, as a transcript or a position string
+// load <file>: replace the game with the one saved in a file
+// h: show how the computer rates each valid position
+// u: take back the last move of the person and the computer's reply
+// r: play again the moves taken back by u
+func (r *Game) playHumanTurn() {
+
+	// Get the valid positions for the player
+	positons := r.ValidPositions()
+
+	// If the player has no valid positions, pass the turn
+	if positons == nil {
+		fmt.Print("Skipping turn.")
+		r.Pass()
+		r.recordClock()
+		return
+	}
+
+	startTime := time.Now()
+
+	// Get next player position or command
+	prompt := "\nPlease enter your next position, such as d3 (or 'h' for a hint, 'u' to undo, 'r' to redo, 'save <file>', 'load <file>'): "
+	for {
+		input := ReadLine(prompt)
+		prompt = "\nInvalid position entered. Please enter your next position: "
+
+		switch strings.ToLower(input) {
+		case "h":
+			r.hint()
+			prompt = "\nPlease enter your next position: "
+			continue
+		case "u":
+			if r.undo() {
+				return
+			}
+			fmt.Print("There is no move to undo.")
+			prompt = "\nPlease enter your next position: "
+			continue
+		case "r":
+			if r.redo() {
+				return
+			}
+			fmt.Print("There is no move to redo.")
+			prompt = "\nPlease enter your next position: "
+			continue
+		}
+
+		fields := strings.Fields(input)
+		if len(fields) == 2 && strings.ToLower(fields[0]) == "save" {
+			r.save(fields[1])
+			prompt = "\nPlease enter your next position: "
+			continue
+		}
+		if len(fields) == 2 && strings.ToLower(fields[0]) == "load" {
+			// The loaded game starts over from its own turn
+			if r.load(fields[1]) {
+				return
+			}
+			prompt = "\nPlease enter your next position: "
+			continue
+		}
+
+		if p, ok := parseValidPosition(r.Reversi, input); ok {
+			r.Clock.Spend(r.Turn(), time.Since(startTime))
+			r.Play(p)
+			r.recordClock()
+			return
+		}
+	}
+}
+
+// Show how the hint analyzer rates each valid position of the current turn, on the board and as a
+// list from best to worst
+func (r *Game) hint() {
+	fmt.Print("Thinking....")
+	evaluations, stats := r.Hints.Analyze(r.Reversi, r.Clock.Budget(r.Reversi))
+	fmt.Print("\n\n")
+
+	labels := make(map[int]string)
+	for _, evaluation := range evaluations {
+		labels[evaluation.Pos] = hintLabel(evaluation, stats)
+	}
+	r.displayBoard(labels)
+	fmt.Print("\n\n")
+
+	for _, evaluation := range evaluations {
+		if stats.Proven {
+			fmt.Printf("%v: ends with a chip difference of %+d with perfect play\n", r.PosName(evaluation.Pos), int(evaluation.Score))
+		} else if evaluation.Visits > 0 {
+			fmt.Printf("%v: wins %.1f%% of %v playouts, score %.2f\n", r.PosName(evaluation.Pos), 100*evaluation.WinRate, evaluation.Visits, evaluation.Score)
+		} else if stats.Depth > 0 {
+			fmt.Printf("%v: scores %v searching %v moves deep\n", r.PosName(evaluation.Pos), evaluation.Score, stats.Depth)
+		} else {
+			fmt.Printf("%v: not searched in time\n", r.PosName(evaluation.Pos))
+		}
+	}
+}
+
+// Get the short rating of a position shown on the board by a hint
+func hintLabel(evaluation engine.Evaluation, stats engine.Stats) string {
+	if stats.Proven {
+		return fmt.Sprintf("%+d", int(evaluation.Score))
+	} else if evaluation.Visits > 0 {
+		return fmt.Sprintf("%.0f%%", 100*evaluation.WinRate)
+	} else if stats.Depth > 0 {
+		return fmt.Sprint(evaluation.Score)
+	}
+	return "?"
+}
+
+// Take back the moves played since the last move of a person, including it, so that it's their turn
+// again. Returns whether there was such a move.
+func (r *Game) undo() bool {
+	undone := 0
+	for {
+		move, ok := r.Undo()
+		if !ok {
+			// Nobody at the terminal has played yet, so play the computer's moves again
+			for ; undone > 0; undone-- {
+				r.Redo()
+			}
+			return false
+		}
+		undone++
+
+		if r.isHuman(move.Color) {
+			r.restoreClock()
+			return true
+		}
+	}
+}
+
+// Play again the moves taken back by undo, up to the next turn of a person. Returns whether there
+// was a move to play again.
+func (r *Game) redo() bool {
+	if _, ok := r.Redo(); !ok {
+		return false
+	}
+	for !r.isHumanTurn() {
+		if _, ok := r.Redo(); !ok {
+			break
+		}
+	}
+	r.restoreClock()
+	return true
+}
+
+// Record the time left on the clocks after the moves played so far, replacing the times recorded
+// after the moves that were taken back
+func (r *Game) recordClock() {
+	moves := len(r.PlayedMoves())
+	times := map[int]time.Duration{engine.Blue: r.Clock.Remaining(engine.Blue), engine.Red: r.Clock.Remaining(engine.Red)}
+	// Moves played without the clock, such as those of a game that was loaded, took no time
+	for len(r.clockTimes) < moves {
+		r.clockTimes = append(r.clockTimes, times)
+	}
+	r.clockTimes = append(r.clockTimes[:moves], times)
+}
+
+// Set the clocks back to the times they had after the moves played so far, once moves are undone or redone
+func (r *Game) restoreClock() {
+	moves := len(r.PlayedMoves())
+	if moves >= len(r.clockTimes) {
+		return
+	}
+	for color, remaining := range r.clockTimes[moves] {
+		r.Clock.SetRemaining(color, remaining)
+	}
+}
+
+// Save the game to the given file
+func (r *Game) save(file string) {
+	if err := ioutil.WriteFile(file, []byte(r.Export()+"\n"), 0644); err != nil {
+		fmt.Printf("Failed to save the game: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved the game to %v.\n", file)
+}
+
+// Replace the game with the one saved in the given file, and return whether it was loaded
+func (r *Game) load(file string) bool {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Failed to load the game: %v\n", err)
+		return false
+	}
+
+	game, err := engine.Parse(string(contents), r.Size())
+	if err != nil {
+		fmt.Printf("Failed to load the game: %v\n", err)
+		return false
+	}
+
+	r.Reversi = game
+	r.clockTimes = nil
+	r.recordClock()
+	fmt.Printf("Loaded the game from %v.\n", file)
+	return true
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"testing"
+	"time"
+)
+
+// Play a move of the person at the terminal that took the given time, as playHumanTurn does
+func playHumanMove(r *Game, spent time.Duration) {
+	r.Clock.Spend(r.Turn(), spent)
+	r.Play(r.ValidPositions()[0])
+	r.recordClock()
+}
+
+func TestUndoRedoRestoresClock(t *testing.T) {
+	start, _ := engine.NewSize(engine.DefaultSize, engine.Blue)
+	players := map[int]engine.Player{engine.Blue: new(Human), engine.Red: engine.NewRandomPlayer()}
+	names := map[int]string{engine.Blue: "human", engine.Red: "random"}
+	control := engine.TimeControl{Clock: time.Minute, Increment: time.Second}
+	r := NewGame(players, names, engine.PlayerConfig{Seed: 1}, control, start)
+
+	type clocks struct{ blue, red time.Duration }
+	clocksOf := func() clocks {
+		return clocks{r.Clock.Remaining(engine.Blue), r.Clock.Remaining(engine.Red)}
+	}
+
+	// After each move of blue and red's reply
+	var times []clocks
+	times = append(times, clocksOf())
+	for i := 0; i < 3; i++ {
+		playHumanMove(r, 5*time.Second)
+		r.PlayMove()
+		times = append(times, clocksOf())
+	}
+	if times[1].blue != time.Minute-4*time.Second {
+		t.Fatalf("blue's clock after a move of 5s = %v, want %v", times[1].blue, time.Minute-4*time.Second)
+	}
+
+	// Undoing takes back a move of each color and their time
+	for i := len(times) - 2; i >= 0; i-- {
+		if !r.undo() {
+			t.Fatalf("can't undo to move %v", 2*i)
+		}
+		if got := clocksOf(); got != times[i] {
+			t.Errorf("clocks after undoing to move %v = %+v, want %+v", 2*i, got, times[i])
+		}
+	}
+	if r.undo() {
+		t.Fatal("undid a move before the start")
+	}
+
+	// Redoing plays the moves again with the time they took
+	for i := 1; i < len(times); i++ {
+		if !r.redo() {
+			t.Fatalf("can't redo to move %v", 2*i)
+		}
+		if got := clocksOf(); got != times[i] {
+			t.Errorf("clocks after redoing to move %v = %+v, want %+v", 2*i, got, times[i])
+		}
+	}
+
+	// A new move after undoing replaces the times of the moves taken back
+	r.undo()
+	playHumanMove(r, 10*time.Second)
+	if want := times[len(times)-2].blue - 9*time.Second; r.Clock.Remaining(engine.Blue) != want {
+		t.Errorf("blue's clock after a new move of 10s = %v, want %v", r.Clock.Remaining(engine.Blue), want)
+	}
+	r.undo()
+	if got := clocksOf(); got != times[len(times)-2] {
+		t.Errorf("clocks after undoing the new move = %+v, want %+v", got, times[len(times)-2])
+	}
+}
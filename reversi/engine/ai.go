@@ -0,0 +1,336 @@
+package engine
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Number of playouts performed for each valid position
+const Playouts int = 500
+
+// Statistics gathered while searching for a move
+type Stats struct {
+	Playouts int
+	Elapsed  time.Duration
+	// Whether the search ran out of time before every playout was done
+	TimeLimitExceeded bool
+	// Whether the move was picked from the opening book rather than searched
+	Book bool
+	// Whether the move was proven best by solving the endgame exactly
+	Proven bool
+	// If Proven, the final disc difference for the color that moved, with perfect play from both colors
+	Score int
+	// Number of positions searched by the endgame solver or the alpha-beta search
+	Nodes int
+	// Depth in moves completed by the alpha-beta search
+	Depth int
+}
+
+// Get the number of playouts performed per second
+func (s Stats) PlayOutsPerSecond() float64 {
+	if s.Elapsed == 0 {
+		return 0
+	}
+	return float64(s.Playouts) / s.Elapsed.Seconds()
+}
+
+// Picks the position to play next during a playout out of the valid positions on the given board,
+// using the random numbers of the worker running the playout
+type Policy func(g *Geometry, rng *rand.Rand, moves Bitboard) int
+
+// Get the playout policy, which picks positions using heuristics or at random
+func PolicyFor(useHeuristics bool) Policy {
+	if useHeuristics {
+		return HeuristicPos
+	}
+	return RandPos
+}
+
+// Seed the random seeds once, they are only used by players that are not given a seed
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Get a random number generator with the given seed, or with a random seed if it is 0
+func NewRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// Get a seed from the given seed and a number, such as a color or the number of a game, so that each
+// number gets unrelated random numbers. A seed of 0 stays 0, for a random seed.
+func DeriveSeed(seed int64, n int64) int64 {
+	if seed == 0 {
+		return 0
+	}
+
+	// Mix the bits with the finalizer of SplitMix64
+	z := splitMix64(uint64(seed) + uint64(n)*0x9e3779b97f4a7c15)
+	if z == 0 {
+		return 1
+	}
+	return int64(z)
+}
+
+// Get a random number generator for each search worker, seeded from the player's generator so that the
+// same seed repeats the same search. Each worker has its own, since a *rand.Rand is not safe for
+// concurrent use.
+func workerRands(rng *rand.Rand, workers int) []*rand.Rand {
+	rngs := make([]*rand.Rand, workers)
+	for w := range rngs {
+		rngs[w] = rand.New(rand.NewSource(rng.Int63()))
+	}
+	return rngs
+}
+
+// Get a random integer within range of given values
+func getRandInt(rng *rand.Rand, min int, max int) int {
+	return rng.Intn(max-min) + min
+}
+
+// Get the number of workers to search with, which defaults to one per CPU core
+func numWorkers(workers int) int {
+	if workers > 0 {
+		return workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Return best position based on heuristics
+// The list of best, bad, and worst positions is derived from the board size in geometry.go
+func HeuristicPos(g *Geometry, rng *rand.Rand, moves Bitboard) int {
+
+	// Return a random position from the best list if not empty
+	if bestList := moves.And(g.corners); !bestList.IsEmpty() {
+		return RandPos(g, rng, bestList)
+	}
+
+	// Return a random position from the good list if not empty
+	if goodList := moves.AndNot(g.badPositions.Or(g.worstPositions)); !goodList.IsEmpty() {
+		return RandPos(g, rng, goodList)
+	}
+
+	// Return a random position from the bad list if not empty
+	if badList := moves.And(g.badPositions); !badList.IsEmpty() {
+		return RandPos(g, rng, badList)
+	}
+
+	// If all of the above failed, return a random position
+	return RandPos(g, rng, moves)
+}
+
+// Get a random position from a given list
+func RandPos(g *Geometry, rng *rand.Rand, moves Bitboard) int {
+	rndNum := getRandInt(rng, 0, moves.Count())
+	return moves.Nth(rndNum)
+}
+
+// Perform a playout for the given instance of reversi and return the winner
+func DoPlayOut(r *Reversi, policy Policy, rng *rand.Rand) int {
+	for {
+		moves := r.Moves()
+
+		// If there are no valid positions, pass the turn to the other player
+		if moves.IsEmpty() {
+			r.SwitchTurns()
+			// If the other player also does not have any valid positions, end the game
+			if r.Moves().IsEmpty() {
+				return r.CheckWin(true)
+			}
+			continue
+		}
+
+		// Get the next move from the policy
+		r.play(policy(r.Geometry, rng, moves))
+	}
+}
+
+// Default scores of a playout that the current turn wins, loses or ties, which make the flat search
+// avoid losing more than it tries to win
+const DefaultWinScore int = 2
+const DefaultLossScore int = -10
+const DefaultTieScore int = 1
+
+// Flat Monte Carlo search, which runs the same number of playouts for each valid position
+type FlatSearch struct {
+	// Picks positions during the playouts
+	Policy Policy
+	// Number of playouts for each valid position
+	Playouts int
+	// Number of goroutines running playouts. If 0, one per CPU core.
+	Workers int
+	// Solve the game exactly once there are at most this many empty positions
+	EndgameEmpties int
+	// Scores of a playout the current turn wins, loses or ties. The position with the best average is played.
+	WinScore  int
+	LossScore int
+	TieScore  int
+	// Source of the random numbers of the playouts. The search is repeated exactly by the same generator
+	// in the same state, as long as the number of workers is the same and the time limit isn't reached.
+	Rand *rand.Rand
+	// Moves played without searching in the positions they have, or nil for no book
+	Book *Book
+	// Called with the progress of the search about every ProgressInterval, or nil
+	Report func(Progress)
+}
+
+// Initialize and return a flat search using the given playout policy
+func NewFlatSearch(policy Policy) *FlatSearch {
+	return &FlatSearch{
+		Policy:         policy,
+		Playouts:       Playouts,
+		EndgameEmpties: DefaultEndgameEmpties,
+		WinScore:       DefaultWinScore,
+		LossScore:      DefaultLossScore,
+		TieScore:       DefaultTieScore,
+		Rand:           NewRand(0),
+	}
+}
+
+// Return the best move for the current turn, or NoMove if there is none. The playouts are shared
+// out between the workers, and the search stops early once the time limit is reached.
+func (f *FlatSearch) BestMove(r *Reversi, timeLimit time.Duration) (int, Stats) {
+
+	var stats Stats
+
+	positions := r.ValidPositions()
+
+	// If there are no valid positions
+	if positions == nil {
+		return NoMove, stats
+	}
+
+	// If the position is in the book, there is no need to search
+	if pos, ok := probeBook(r, f.Book, f.Rand, &stats); ok {
+		return pos, stats
+	}
+
+	startTime := time.Now()
+
+	// If the endgame is small enough, solve it instead
+	if pos, ok := solveEndgame(r, f.EndgameEmpties, &stats); ok {
+		stats.Elapsed = time.Since(startTime)
+		return pos, stats
+	}
+
+	// Get the best next move, comparing the average score per playout since the
+	// positions may not have had the same number of playouts
+	evaluations, stats := f.playOuts(r, positions, startTime, timeLimit)
+	return evaluations[0].Pos, stats
+}
+
+// Return the evaluations of the valid positions for the current turn, best first, or nil if there are none
+func (f *FlatSearch) Analyze(r *Reversi, timeLimit time.Duration) ([]Evaluation, Stats) {
+	return analyze(r, f.EndgameEmpties, func(positions []int, startTime time.Time) ([]Evaluation, Stats) {
+		return f.playOuts(r, positions, startTime, timeLimit)
+	})
+}
+
+// Run the playouts of the given positions until they are done or the time limit is reached, and
+// return the evaluations of the positions sorted by average score, best first
+func (f *FlatSearch) playOuts(r *Reversi, positions []int, startTime time.Time, timeLimit time.Duration) ([]Evaluation, Stats) {
+
+	var stats Stats
+	policy := f.Policy
+	playouts := f.Playouts
+	workers := numWorkers(f.Workers)
+
+	// Every playout is numbered, playout i is for position i % len(positions). Worker w runs the playouts
+	// w, w + workers, w + 2 * workers and so on, so the positions take turns and all of them get about
+	// the same number of playouts if the time runs out. Unlike taking the next number from a shared
+	// counter, this runs the same playouts with the same random numbers every time.
+	var timeLimitExceeded int32
+	total := len(positions) * playouts
+	rngs := workerRands(f.Rand, workers)
+	tracker := newProgressTracker(f.Report, positions, workers, startTime, false)
+
+	// Scores, wins and number of playouts of each position per worker, merged once all workers are done
+	workerScores := make([][]int, workers)
+	workerWins := make([][]float64, workers)
+	workerPlayOuts := make([][]int, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			rng := rngs[w]
+			scores := make([]int, len(positions))
+			wins := make([]float64, len(positions))
+			playOuts := make([]int, len(positions))
+
+			for i := w; i < total; i += workers {
+				// If the time limit has elapsed since we started all playouts, end early
+				if time.Since(startTime) > timeLimit {
+					atomic.StoreInt32(&timeLimitExceeded, 1)
+					break
+				}
+
+				ind := i % len(positions)
+
+				// Make a deep copy to perform playouts on
+				cpy := r.searchCopy()
+				cpy.play(positions[ind])
+				result := DoPlayOut(cpy, policy, rng)
+				playOuts[ind] += 1
+
+				// Add weighted scores based on result
+				// If the current user has won
+				if result == r.turn {
+					scores[ind] += f.WinScore
+					wins[ind] += 1
+					// If the opponent has won
+				} else if result == r.turn*-1 {
+					scores[ind] += f.LossScore
+				} else {
+					// If it's a tie
+					scores[ind] += f.TieScore
+					wins[ind] += 0.5
+				}
+
+				if tracker != nil && (i/workers+1)%progressCheckInterval == 0 {
+					tracker.update(w, func(visits []int, trackedScores []float64) {
+						copy(visits, playOuts)
+						for ind, score := range scores {
+							trackedScores[ind] = float64(score)
+						}
+					})
+				}
+			}
+
+			workerScores[w] = scores
+			workerWins[w] = wins
+			workerPlayOuts[w] = playOuts
+		}(w)
+	}
+	wg.Wait()
+
+	stats.Elapsed = time.Since(startTime)
+	stats.TimeLimitExceeded = timeLimitExceeded == 1
+
+	// Merge the scores of every worker
+	evaluations := make([]Evaluation, len(positions))
+	for ind, pos := range positions {
+		score, wins, playOuts := 0, float64(0), 0
+		for w := 0; w < workers; w++ {
+			score += workerScores[w][ind]
+			wins += workerWins[w][ind]
+			playOuts += workerPlayOuts[w][ind]
+		}
+		stats.Playouts += playOuts
+
+		evaluations[ind] = Evaluation{Pos: pos, Visits: playOuts, WinRate: winRate(wins, playOuts)}
+		if playOuts > 0 {
+			evaluations[ind].Score = float64(score) / float64(playOuts)
+		}
+	}
+	sortByScore(evaluations, true)
+
+	return evaluations, stats
+}
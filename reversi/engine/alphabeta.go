@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Scores a position on the given board from the point of view of own, the color whose turn it is.
+// Higher is better for own.
+type Evaluator func(g *Geometry, own, opp Bitboard) int
+
+// Scores of the heuristic evaluation for each chip on the tables defined in geometry.go
+const cornerWeight int = 25
+const badWeight int = -8
+const worstWeight int = -12
+
+// Score of each possible move more than the opponent
+const mobilityWeight int = 2
+
+// Finished games are scored as the chip difference times this, so that a won game is always
+// better than any evaluation and a lost game worse
+const finalScoreWeight int = 1000
+
+// Check the clock once every this many positions, rather than at every position
+const timeCheckInterval int = 1024
+
+// Evaluate a position using the same tables as the heuristic playouts: corners are the best
+// positions, the positions next to them are bad and the ones diagonally next to them are the worst.
+// Having more valid moves than the opponent is also good.
+func HeuristicEval(g *Geometry, own, opp Bitboard) int {
+	score := cornerWeight * (own.And(g.corners).Count() - opp.And(g.corners).Count())
+	score += badWeight * (own.And(g.badPositions).Count() - opp.And(g.badPositions).Count())
+	score += worstWeight * (own.And(g.worstPositions).Count() - opp.And(g.worstPositions).Count())
+	score += mobilityWeight * (g.validMoves(own, opp).Count() - g.validMoves(opp, own).Count())
+	return score
+}
+
+// Get the final chip difference of an alpha-beta score of a game that ends within the search, and whether
+// it does. The other scores are the evaluations of the positions at the maximum depth.
+func FinalChipDifference(score int) (int, bool) {
+	if score >= finalScoreWeight || score <= -finalScoreWeight {
+		return score / finalScoreWeight, true
+	}
+	return 0, false
+}
+
+// Evaluate a position by the chip difference alone
+func ChipEval(g *Geometry, own, opp Bitboard) int {
+	return own.Count() - opp.Count()
+}
+
+// Iterative deepening alpha-beta (negamax) search with a pluggable evaluation function
+type AlphaBeta struct {
+	// Scores the positions at the maximum depth
+	Evaluator Evaluator
+	// Maximum depth in moves to search to. If 0, the search deepens until the time runs out.
+	MaxDepth int
+	// Solve the game exactly once there are at most this many empty positions
+	EndgameEmpties int
+	// Moves played without searching in the positions they have, or nil for no book
+	Book *Book
+	// Source of the random numbers that pick between the moves of the book
+	Rand *rand.Rand
+	// Results of the positions searched, kept from one search to the next, or nil to search without a table
+	Table *TranspositionTable
+	// Called with the progress of the search whenever a depth is completed, or nil
+	Report func(Progress)
+}
+
+// State of a single search
+type alphaBetaSearch struct {
+	*Geometry
+	evaluator Evaluator
+	table     *TranspositionTable
+	deadline  time.Time
+	nodes     int
+	// Whether the time ran out, in which case the results of the current depth are not usable
+	aborted bool
+}
+
+// Initialize and return an alpha-beta search using the given evaluation function
+func NewAlphaBeta(evaluator Evaluator) *AlphaBeta {
+	return &AlphaBeta{
+		Evaluator:      evaluator,
+		EndgameEmpties: DefaultEndgameEmpties,
+		Rand:           NewRand(0),
+		Table:          NewTranspositionTable(DefaultTableEntries),
+	}
+}
+
+// Negamax search with alpha-beta pruning of the position to the given depth, where color is the color
+// whose turn it is and hash the hash of the position. passed is true if the opponent has just passed the turn.
+func (s *alphaBetaSearch) negamax(own, opp Bitboard, hash uint64, color, depth, alpha, beta int, passed bool) int {
+	s.nodes += 1
+
+	// If the time has run out, stop searching, the result will be thrown away
+	if s.nodes%timeCheckInterval == 0 && time.Now().After(s.deadline) {
+		s.aborted = true
+	}
+	if s.aborted {
+		return 0
+	}
+
+	moves := s.validMoves(own, opp)
+
+	if moves.IsEmpty() {
+		// If neither color can move, the game is over
+		if passed {
+			return finalScoreWeight * (own.Count() - opp.Count())
+		}
+		// Otherwise pass the turn
+		return -s.negamax(opp, own, hash^zobristTurn, -color, depth, -beta, -alpha, true)
+	}
+
+	if depth == 0 {
+		return s.evaluator(s.Geometry, own, opp)
+	}
+
+	// A search of the position at least as deep may already have the score, or at least a bound that
+	// narrows the window, and its best move is searched first
+	first := NoMove
+	if s.table != nil {
+		if entry, ok := s.table.Probe(hash, own, opp); ok {
+			if entry.Depth >= depth {
+				if entry.Bound == ExactScore {
+					return entry.Score
+				} else if entry.Bound == LowerBound && entry.Score > alpha {
+					alpha = entry.Score
+				} else if entry.Bound == UpperBound && entry.Score < beta {
+					beta = entry.Score
+				}
+				if alpha >= beta {
+					return entry.Score
+				}
+			}
+			if entry.Best != NoMove && moves.Has(entry.Best) {
+				first = entry.Best
+			}
+		}
+	}
+
+	originalAlpha := alpha
+	best := NoMove
+	for {
+		pos := first
+		if pos == NoMove {
+			if moves.IsEmpty() {
+				break
+			}
+			pos = moves.First()
+		}
+		moves = moves.AndNot(bit(pos))
+		first = NoMove
+
+		flipped := s.flips(own, opp, pos)
+		newOwn, newOpp := played(own, opp, flipped, pos)
+		score := -s.negamax(newOpp, newOwn, hash^zobristTurn^moveHash(color, pos, flipped), -color, depth-1, -beta, -alpha, false)
+
+		if score > alpha {
+			alpha = score
+			best = pos
+			// The opponent would never allow this line
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+
+	if s.table != nil && !s.aborted {
+		bound := ExactScore
+		if alpha <= originalAlpha {
+			bound = UpperBound
+		} else if alpha >= beta {
+			bound = LowerBound
+		}
+		s.table.Store(hash, own, opp, TableEntry{Depth: depth, Score: alpha, Bound: bound, Best: best})
+	}
+
+	return alpha
+}
+
+// Return the best move for the current turn, or NoMove if there is none. The search goes one move
+// deeper at a time, starting each depth with the best move of the previous one, and returns the
+// best move of the deepest depth completed within the time limit.
+func (a *AlphaBeta) BestMove(r *Reversi, timeLimit time.Duration) (int, Stats) {
+
+	var stats Stats
+
+	positions := r.ValidPositions()
+
+	// If there are no valid positions
+	if positions == nil {
+		return NoMove, stats
+	}
+
+	// If the position is in the book, there is no need to search
+	if pos, ok := probeBook(r, a.Book, a.Rand, &stats); ok {
+		return pos, stats
+	}
+
+	startTime := time.Now()
+
+	// If the endgame is small enough, solve it instead
+	if pos, ok := solveEndgame(r, a.EndgameEmpties, &stats); ok {
+		stats.Elapsed = time.Since(startTime)
+		return pos, stats
+	}
+
+	// If there is only one valid position, there is nothing to search
+	if len(positions) == 1 {
+		return positions[0], stats
+	}
+
+	evaluations, stats := a.deepen(r, positions, startTime, timeLimit, false)
+	return evaluations[0].Pos, stats
+}
+
+// Return the evaluations of the valid positions for the current turn at the deepest depth completed
+// within the time limit, best first, or nil if there are none
+func (a *AlphaBeta) Analyze(r *Reversi, timeLimit time.Duration) ([]Evaluation, Stats) {
+	return analyze(r, a.EndgameEmpties, func(positions []int, startTime time.Time) ([]Evaluation, Stats) {
+		return a.deepen(r, positions, startTime, timeLimit, true)
+	})
+}
+
+// Search the given positions one move deeper at a time, starting each depth with the best position of the
+// previous one, and return their evaluations at the deepest depth completed within the time limit, best
+// first. If exact is false, only the best position is searched with the full window, which prunes far more,
+// and the scores of the others are only upper bounds.
+func (a *AlphaBeta) deepen(r *Reversi, positions []int, startTime time.Time, timeLimit time.Duration, exact bool) ([]Evaluation, Stats) {
+
+	var stats Stats
+
+	s := &alphaBetaSearch{Geometry: r.Geometry, evaluator: a.Evaluator, table: a.Table, deadline: startTime.Add(timeLimit)}
+	if a.Table != nil {
+		a.Table.NewSearch()
+	}
+	own, opp := r.ownAndOpp()
+	evaluations := []Evaluation{{Pos: positions[0]}}
+
+	// Searching deeper than the number of empty positions can't find anything new
+	maxDepth := r.Empties()
+	if a.MaxDepth > 0 && a.MaxDepth < maxDepth {
+		maxDepth = a.MaxDepth
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		alpha := math.MinInt32
+		depthEvaluations := make([]Evaluation, 0, len(positions))
+
+		for _, pos := range positions {
+			beta := -alpha
+			if exact {
+				beta = math.MaxInt32
+			}
+
+			flipped := s.flips(own, opp, pos)
+			newOwn, newOpp := played(own, opp, flipped, pos)
+			hash := r.hash ^ zobristTurn ^ moveHash(r.turn, pos, flipped)
+			score := -s.negamax(newOpp, newOwn, hash, -r.turn, depth-1, math.MinInt32, beta, false)
+
+			if s.aborted {
+				break
+			}
+			if score > alpha {
+				alpha = score
+			}
+			depthEvaluations = append(depthEvaluations, Evaluation{Pos: pos, Score: float64(score)})
+		}
+
+		// If the time ran out during this depth, keep the evaluations of the previous one
+		if s.aborted {
+			stats.TimeLimitExceeded = true
+			break
+		}
+
+		sortByScore(depthEvaluations, false)
+		evaluations = depthEvaluations
+		stats.Depth = depth
+
+		if a.Report != nil {
+			a.Report(Progress{Best: evaluations[0].Pos, Nodes: s.nodes, Depth: depth, Elapsed: time.Since(startTime)})
+		}
+
+		// Search the best move first at the next depth, it prunes the most
+		for i, pos := range positions {
+			if pos == evaluations[0].Pos {
+				positions[0], positions[i] = positions[i], positions[0]
+				break
+			}
+		}
+	}
+
+	stats.Elapsed = time.Since(startTime)
+	stats.Nodes = s.nodes
+
+	return evaluations, stats
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Get the score of a position searched to the given depth with the given evaluation, by searching every
+// move without pruning or a table, scored as alpha-beta scores it. passed is true if the opponent has just
+// passed the turn.
+func fixedDepthNegamax(g *Geometry, evaluator Evaluator, own, opp Bitboard, depth int, passed bool) int {
+	moves := g.validMoves(own, opp)
+	if moves.IsEmpty() {
+		if passed {
+			return finalScoreWeight * (own.Count() - opp.Count())
+		}
+		return -fixedDepthNegamax(g, evaluator, opp, own, depth, true)
+	}
+	if depth == 0 {
+		return evaluator(g, own, opp)
+	}
+
+	best := math.MinInt32
+	for ; !moves.IsEmpty(); moves = moves.withoutFirst() {
+		pos := moves.First()
+		newOwn, newOpp := played(own, opp, g.flips(own, opp, pos), pos)
+		if score := -fixedDepthNegamax(g, evaluator, newOpp, newOwn, depth-1, false); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// Get a game of random moves from the start with the given number of positions played, or nil if the
+// game ended before
+func randomMidgame(rng *rand.Rand, size, positions int) *Reversi {
+	r := randomGame(rng, size, Blue, positions)
+	if r.IsOver() || r.Moves().IsEmpty() {
+		return nil
+	}
+	return r
+}
+
+func TestAlphaBetaMatchesNegamax(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	evaluators := map[string]Evaluator{"heuristic": HeuristicEval, "chips": ChipEval}
+	for _, size := range []int{6, 8} {
+		for searched := 0; searched < 6; {
+			r := randomMidgame(rng, size, 4+rng.Intn(size*size-12))
+			if r == nil {
+				continue
+			}
+			searched++
+			own, opp := r.ownAndOpp()
+
+			for name, evaluator := range evaluators {
+				// The table is kept from one depth to the next, as the players keep it from one move to the next
+				tables := map[string]*TranspositionTable{
+					"no table":     nil,
+					"a table":      NewTranspositionTable(DefaultTableEntries),
+					"a tiny table": NewTranspositionTable(4),
+				}
+				for depth := 1; depth <= 4; depth++ {
+					want := fixedDepthNegamax(r.Geometry, evaluator, own, opp, depth, false)
+					for tableName, table := range tables {
+						s := &alphaBetaSearch{Geometry: r.Geometry, evaluator: evaluator, table: table, deadline: time.Now().Add(time.Hour)}
+						if table != nil {
+							table.NewSearch()
+						}
+						if score := s.negamax(own, opp, r.Hash(), r.Turn(), depth, math.MinInt32, math.MaxInt32, false); score != want {
+							t.Errorf("%v with %v evaluation at depth %v: alpha-beta with %v scores %v, negamax %v", r.Position(), name, depth, tableName, score, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAlphaBetaAnalyzeMatchesNegamax(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for searched := 0; searched < 6; {
+		r := randomMidgame(rng, 8, 4+rng.Intn(40))
+		if r == nil || len(r.ValidPositions()) < 2 {
+			continue
+		}
+		searched++
+
+		a := NewAlphaBeta(HeuristicEval)
+		a.MaxDepth = 4
+		a.EndgameEmpties = 0
+		evaluations, stats := a.Analyze(r, time.Hour)
+		if stats.Depth != a.MaxDepth {
+			t.Fatalf("%v: searched %v moves deep, want %v", r.Position(), stats.Depth, a.MaxDepth)
+		}
+
+		// Every move gets its score, and the best is first
+		best := math.MinInt32
+		for _, evaluation := range evaluations {
+			r.Play(evaluation.Pos)
+			own, opp := r.ownAndOpp()
+			want := -fixedDepthNegamax(r.Geometry, HeuristicEval, own, opp, a.MaxDepth-1, false)
+			r.Undo()
+			if int(evaluation.Score) != want {
+				t.Errorf("%v: %v scores %v, negamax %v", r.Position(), r.PosName(evaluation.Pos), evaluation.Score, want)
+			}
+			if want > best {
+				best = want
+			}
+		}
+		if len(evaluations) != len(r.ValidPositions()) || int(evaluations[0].Score) != best {
+			t.Errorf("%v: evaluations %+v, want one for each valid position with the best score %v first", r.Position(), evaluations, best)
+		}
+
+		// The move played is one with the best score
+		pos, _ := a.BestMove(r, time.Hour)
+		r.Play(pos)
+		own, opp := r.ownAndOpp()
+		if score := -fixedDepthNegamax(r.Geometry, HeuristicEval, own, opp, a.MaxDepth-1, false); score != best {
+			t.Errorf("%v: plays %v, which scores %v rather than %v", r.Position(), r.PosName(pos), score, best)
+		}
+	}
+}
+
+func TestAlphaBetaUsesEvaluator(t *testing.T) {
+	r, _ := ParseTranscript("f5d6c3d3c4f4", DefaultSize)
+	positions := r.ValidPositions()
+	target := positions[len(positions)-1]
+
+	// The evaluation scores the opponent's positions after each move, and rates the moves to the target
+	// better than any other, which the heuristics wouldn't
+	calls := 0
+	evaluator := func(g *Geometry, own, opp Bitboard) int {
+		calls++
+		if opp.Has(target) {
+			return -100
+		}
+		return 0
+	}
+
+	a := NewAlphaBeta(evaluator)
+	a.MaxDepth = 1
+	a.Table = nil
+	a.EndgameEmpties = 0
+	pos, stats := a.BestMove(r, time.Hour)
+	if pos != target {
+		t.Errorf("plays %v, want %v which the evaluation rates best", r.PosName(pos), r.PosName(target))
+	}
+	if calls != len(positions) || stats.Depth != 1 {
+		t.Errorf("evaluated %v positions %v moves deep, want %v 1 move deep", calls, stats.Depth, len(positions))
+	}
+}
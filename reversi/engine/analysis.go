@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"sort"
+	"time"
+)
+
+// How a search rated one of the valid positions
+type Evaluation struct {
+	Pos int
+	// Number of playouts run for the position, or visits of its node in the search tree.
+	// 0 for searches without playouts.
+	Visits int
+	// Fraction of the playouts won by the current turn, ties counting half, if Visits is not 0
+	WinRate float64
+	// Score of the position for the current turn, higher is better: the average weighted playout
+	// score of FlatSearch, the win rate of MCTS, the evaluation of AlphaBeta, or the final disc
+	// difference with perfect play if the endgame was solved
+	Score float64
+}
+
+// A player that can rate every valid position, not only pick the best one
+type Analyzer interface {
+	Player
+	// Return the evaluations of the valid positions for the current turn within the time limit, best first,
+	// or nil if there are none
+	Analyze(r *Reversi, timeLimit time.Duration) ([]Evaluation, Stats)
+}
+
+// Sort evaluations best first by score. Positions without playouts, which the time didn't allow for,
+// are last. Positions with the same score keep their order.
+func sortByScore(evaluations []Evaluation, playouts bool) {
+	sort.SliceStable(evaluations, func(i, j int) bool {
+		if playouts && (evaluations[i].Visits == 0) != (evaluations[j].Visits == 0) {
+			return evaluations[j].Visits == 0
+		}
+		return evaluations[i].Score > evaluations[j].Score
+	})
+}
+
+// Solve every valid position exactly if there are at most the given number of empty positions,
+// filling in the stats. Returns the evaluations, best first, and whether the game was solved.
+func analyzeEndgame(r *Reversi, endgameEmpties int, stats *Stats) ([]Evaluation, bool) {
+	if r.Empties() > endgameEmpties {
+		return nil, false
+	}
+
+	own, opp := r.ownAndOpp()
+	s := newEndgameSearch(r)
+	var evaluations []Evaluation
+
+	// Search every position with the full window, so that each score is exact rather than a bound
+	for _, pos := range r.ValidPositions() {
+		flipped := r.flips(own, opp, pos)
+		newOwn, newOpp := played(own, opp, flipped, pos)
+		hash := r.hash ^ zobristTurn ^ moveHash(r.turn, pos, flipped)
+		score := -s.solve(newOpp, newOwn, hash, -r.turn, -r.cells, r.cells, false)
+		evaluations = append(evaluations, Evaluation{Pos: pos, Score: float64(score)})
+	}
+	sortByScore(evaluations, false)
+
+	stats.Proven = true
+	stats.Score = int(evaluations[0].Score)
+	stats.Nodes = s.nodes
+
+	return evaluations, true
+}
+
+// Get the evaluations of the valid positions for the current turn, or nil if there are none.
+// The endgame is solved exactly and otherwise search rates the positions.
+func analyze(r *Reversi, endgameEmpties int, search func(positions []int, startTime time.Time) ([]Evaluation, Stats)) ([]Evaluation, Stats) {
+	var stats Stats
+
+	positions := r.ValidPositions()
+
+	// If there are no valid positions
+	if positions == nil {
+		return nil, stats
+	}
+
+	startTime := time.Now()
+
+	// If the endgame is small enough, solve it instead
+	if evaluations, ok := analyzeEndgame(r, endgameEmpties, &stats); ok {
+		stats.Elapsed = time.Since(startTime)
+		return evaluations, stats
+	}
+
+	return search(positions, startTime)
+}
+
+// Get the win rate of the given number of wins out of the given number of playouts
+func winRate(wins float64, playouts int) float64 {
+	if playouts == 0 {
+		return 0
+	}
+	return wins / float64(playouts)
+}
@@ -0,0 +1,246 @@
+package engine
+
+import "math/bits"
+
+// One bit per position of the board, where bit i is set if position i is occupied. Boards have up
+// to 128 positions, so the bits are kept in two words: positions 0 to 63 in lo and the rest in hi.
+type Bitboard struct {
+	lo uint64
+	hi uint64
+}
+
+// Get a bitboard with only the given position set
+func bit(pos int) Bitboard {
+	if pos < 64 {
+		return Bitboard{lo: 1 << uint(pos)}
+	}
+	return Bitboard{hi: 1 << uint(pos-64)}
+}
+
+// Get a bitboard with the given positions set
+func BitboardOf(positions ...int) Bitboard {
+	var b Bitboard
+	for _, pos := range positions {
+		b = b.Or(bit(pos))
+	}
+	return b
+}
+
+// Get the positions set in either bitboard
+func (b Bitboard) Or(c Bitboard) Bitboard {
+	return Bitboard{lo: b.lo | c.lo, hi: b.hi | c.hi}
+}
+
+// Get the positions set in both bitboards
+func (b Bitboard) And(c Bitboard) Bitboard {
+	return Bitboard{lo: b.lo & c.lo, hi: b.hi & c.hi}
+}
+
+// Get the positions set in b but not in c
+func (b Bitboard) AndNot(c Bitboard) Bitboard {
+	return Bitboard{lo: b.lo &^ c.lo, hi: b.hi &^ c.hi}
+}
+
+// Get the positions set in exactly one of the bitboards
+func (b Bitboard) Xor(c Bitboard) Bitboard {
+	return Bitboard{lo: b.lo ^ c.lo, hi: b.hi ^ c.hi}
+}
+
+// Return whether no position is set
+func (b Bitboard) IsEmpty() bool {
+	return b.lo|b.hi == 0
+}
+
+// Return whether the given position is set
+func (b Bitboard) Has(pos int) bool {
+	if pos < 64 {
+		return b.lo&(1<<uint(pos)) != 0
+	}
+	return b.hi&(1<<uint(pos-64)) != 0
+}
+
+// Get the number of positions set
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(b.lo) + bits.OnesCount64(b.hi)
+}
+
+// Get the lowest position set, or NoMove if the bitboard is empty
+func (b Bitboard) First() int {
+	if b.lo != 0 {
+		return bits.TrailingZeros64(b.lo)
+	}
+	if b.hi != 0 {
+		return 64 + bits.TrailingZeros64(b.hi)
+	}
+	return NoMove
+}
+
+// Get the bitboard without its lowest position
+func (b Bitboard) withoutFirst() Bitboard {
+	if b.lo != 0 {
+		return Bitboard{lo: b.lo & (b.lo - 1), hi: b.hi}
+	}
+	return Bitboard{hi: b.hi & (b.hi - 1)}
+}
+
+// Get the n-th lowest position set (counting from 0)
+func (b Bitboard) Nth(n int) int {
+	for ; n > 0; n-- {
+		b = b.withoutFirst()
+	}
+	return b.First()
+}
+
+// Append the positions set to dst in increasing order
+func (b Bitboard) AppendPositions(dst []int) []int {
+	for ; !b.IsEmpty(); b = b.withoutFirst() {
+		dst = append(dst, b.First())
+	}
+	return dst
+}
+
+// Get the positions set in increasing order, or nil if there are none
+func (b Bitboard) Positions() []int {
+	if b.IsEmpty() {
+		return nil
+	}
+	return b.AppendPositions(make([]int, 0, b.Count()))
+}
+
+// Move every position n positions higher, for 0 < n < 64
+func (b Bitboard) shiftLeft(n uint) Bitboard {
+	return Bitboard{lo: b.lo << n, hi: b.hi<<n | b.lo>>(64-n)}
+}
+
+// Move every position n positions lower, for 0 < n < 64
+func (b Bitboard) shiftRight(n uint) Bitboard {
+	return Bitboard{lo: b.lo>>n | b.hi<<(64-n), hi: b.hi >> n}
+}
+
+// The eight directions a line of chips can be captured in
+const (
+	up = iota
+	down
+	left
+	right
+	upLeft
+	upRight
+	downLeft
+	downRight
+	numDirections
+)
+
+// How to move every chip one step in a direction: shift the bits by n, towards the higher positions if
+// down is set, then drop the chips that wrapped around to the other side of the board or fell off it
+type direction struct {
+	down bool
+	n    uint
+	mask Bitboard
+}
+
+// Get the eight directions on the given board
+func (g *Geometry) directions() [numDirections]direction {
+	n := uint(g.size)
+	return [numDirections]direction{
+		up:        {down: false, n: n, mask: g.all},
+		down:      {down: true, n: n, mask: g.all},
+		left:      {down: false, n: 1, mask: g.notRightColumn},
+		right:     {down: true, n: 1, mask: g.notLeftColumn},
+		upLeft:    {down: false, n: n + 1, mask: g.notRightColumn},
+		upRight:   {down: false, n: n - 1, mask: g.notLeftColumn},
+		downLeft:  {down: true, n: n - 1, mask: g.notRightColumn},
+		downRight: {down: true, n: n + 1, mask: g.notLeftColumn},
+	}
+}
+
+// Move every chip one step in the direction, dropping the ones that fall off the board
+func (d *direction) shift(b Bitboard) Bitboard {
+	if d.down {
+		return b.shiftLeft(d.n).And(d.mask)
+	}
+	return b.shiftRight(d.n).And(d.mask)
+}
+
+// Same as shift, for boards whose positions all fit in the low word
+func (d *direction) shift64(b uint64) uint64 {
+	if d.down {
+		return (b << d.n) & d.mask.lo
+	}
+	return (b >> d.n) & d.mask.lo
+}
+
+// Get the empty positions where own can place a chip, as in chips of opp are sandwiched
+// between the empty position and another chip of own
+func (g *Geometry) validMoves(own, opp Bitboard) Bitboard {
+	// Boards up to 8x8 only need the low word, which is about twice as fast
+	if g.cells <= 64 {
+		return Bitboard{lo: g.validMoves64(own.lo, opp.lo)}
+	}
+
+	empty := g.all.AndNot(own.Or(opp))
+	var moves Bitboard
+
+	for dir := range g.dirs {
+		d := &g.dirs[dir]
+
+		// Grow a line of opp chips from each own chip, a line has at most size - 2 chips
+		line := d.shift(own).And(opp)
+		for i := 3; i < g.size; i++ {
+			line = line.Or(d.shift(line).And(opp))
+		}
+
+		// The position right after a line is a move if it's empty
+		moves = moves.Or(d.shift(line).And(empty))
+	}
+
+	return moves
+}
+
+// Same as validMoves, for boards whose positions all fit in the low word
+func (g *Geometry) validMoves64(own, opp uint64) uint64 {
+	empty := g.all.lo &^ (own | opp)
+	var moves uint64
+
+	for dir := range g.dirs {
+		d := &g.dirs[dir]
+
+		line := d.shift64(own) & opp
+		for i := 3; i < g.size; i++ {
+			line |= d.shift64(line) & opp
+		}
+
+		moves |= d.shift64(line) & empty
+	}
+
+	return moves
+}
+
+// Get the chips of opp that are flipped when own places a chip in the given position
+func (g *Geometry) flips(own, opp Bitboard, pos int) Bitboard {
+	placed := bit(pos)
+	var flipped Bitboard
+
+	for dir := range g.dirs {
+		d := &g.dirs[dir]
+
+		// Walk over the opp chips in this direction
+		var line Bitboard
+		curr := d.shift(placed)
+		for !curr.And(opp).IsEmpty() {
+			line = line.Or(curr)
+			curr = d.shift(curr)
+		}
+
+		// The line is captured only if it ends with an own chip
+		if !curr.And(own).IsEmpty() {
+			flipped = flipped.Or(line)
+		}
+	}
+
+	return flipped
+}
+
+// Get the chips of own and opp after own plays the given position, which flips the given chips of opp
+func played(own, opp, flipped Bitboard, pos int) (Bitboard, Bitboard) {
+	return own.Or(flipped).Or(bit(pos)), opp.AndNot(flipped)
+}
@@ -0,0 +1,179 @@
+package engine
+
+import "testing"
+
+// Count the games of the given number of moves from a game, where a pass is a move and a game that is over
+// counts once however many moves are left
+func perft(r *Reversi, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := r.Moves()
+	if moves.IsEmpty() {
+		if r.IsOver() {
+			return 1
+		}
+		r.Pass()
+		count := perft(r, depth-1)
+		r.Undo()
+		return count
+	}
+
+	count := 0
+	for _, pos := range moves.Positions() {
+		r.Play(pos)
+		count += perft(r, depth-1)
+		r.Undo()
+	}
+	return count
+}
+
+// A board of cells that are Blue, Red or Empty, played by checking every line from a position one
+// cell at a time, to check the bitboards against
+type naiveBoard struct {
+	size  int
+	cells []int
+}
+
+// Initialize and return a board with the four starting chips, as NewSize places them
+func newNaiveBoard(size int) *naiveBoard {
+	b := &naiveBoard{size: size, cells: make([]int, size*size)}
+	center := size / 2
+	b.cells[(center-1)*size+center-1] = Red
+	b.cells[center*size+center] = Red
+	b.cells[(center-1)*size+center] = Blue
+	b.cells[center*size+center-1] = Blue
+	return b
+}
+
+// Get the positions of the opposite color that the given color playing the given position flips
+func (b *naiveBoard) flips(color, pos int) []int {
+	if b.cells[pos] != Empty {
+		return nil
+	}
+
+	var flipped []int
+	row, col := pos/b.size, pos%b.size
+	for dRow := -1; dRow <= 1; dRow++ {
+		for dCol := -1; dCol <= 1; dCol++ {
+			if dRow == 0 && dCol == 0 {
+				continue
+			}
+			var line []int
+			r, c := row+dRow, col+dCol
+			for r >= 0 && r < b.size && c >= 0 && c < b.size && b.cells[r*b.size+c] == -color {
+				line = append(line, r*b.size+c)
+				r, c = r+dRow, c+dCol
+			}
+			if len(line) > 0 && r >= 0 && r < b.size && c >= 0 && c < b.size && b.cells[r*b.size+c] == color {
+				flipped = append(flipped, line...)
+			}
+		}
+	}
+	return flipped
+}
+
+// Get the positions the given color can play
+func (b *naiveBoard) moves(color int) []int {
+	var moves []int
+	for pos := range b.cells {
+		if len(b.flips(color, pos)) > 0 {
+			moves = append(moves, pos)
+		}
+	}
+	return moves
+}
+
+// Count the games of the given number of moves from the board with the given color to move, as perft does
+func (b *naiveBoard) perft(color, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := b.moves(color)
+	if len(moves) == 0 {
+		if len(b.moves(-color)) == 0 {
+			return 1
+		}
+		return b.perft(-color, depth-1)
+	}
+
+	count := 0
+	for _, pos := range moves {
+		flipped := b.flips(color, pos)
+		b.cells[pos] = color
+		for _, f := range flipped {
+			b.cells[f] = color
+		}
+		count += b.perft(-color, depth-1)
+		b.cells[pos] = Empty
+		for _, f := range flipped {
+			b.cells[f] = -color
+		}
+	}
+	return count
+}
+
+// The counts of the standard board are the published ones. The other sizes are counted by the naive board
+// as well, by TestPerftMatchesNaiveBoard; 60060 is every game of the 4x4 board.
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		size, depth, count int
+	}{
+		{4, 1, 4},
+		{4, 4, 128},
+		{4, 11, 50704},
+		{4, 16, 60060},
+		{6, 1, 4},
+		{6, 5, 1364},
+		{6, 7, 47740},
+		{8, 1, 4},
+		{8, 2, 12},
+		{8, 3, 56},
+		{8, 4, 244},
+		{8, 5, 1396},
+		{8, 6, 8200},
+		{8, 7, 55092},
+		{8, 8, 390216},
+		{8, 9, 3005288},
+		{10, 1, 4},
+		{10, 4, 244},
+		{10, 6, 8200},
+	}
+
+	for _, test := range tests {
+		if test.count > 100000 && testing.Short() {
+			continue
+		}
+		r, err := NewSize(test.size, Blue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := perft(r, test.depth); count != test.count {
+			t.Errorf("perft of %vx%v at depth %v = %v, want %v", test.size, test.size, test.depth, count, test.count)
+		}
+	}
+}
+
+func TestPerftMatchesNaiveBoard(t *testing.T) {
+	tests := []struct {
+		size, depth int
+	}{
+		{4, 16},
+		{6, 7},
+		{8, 6},
+		{10, 5},
+	}
+
+	for _, test := range tests {
+		r, err := NewSize(test.size, Blue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := perft(r, test.depth)
+		if want := newNaiveBoard(test.size).perft(Blue, test.depth); count != want {
+			t.Errorf("perft of %vx%v at depth %v = %v, the naive board counts %v", test.size, test.size, test.depth, count, want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Weighted moves to play in known positions, usually openings, so that the players don't search
+// where the best moves are already known. A book has positions of any board size.
+//
+// The eight rotations and reflections of a position play the same way, so a position is stored once,
+// as the one of its symmetric positions with the smallest bitboards, and the book's moves are turned
+// back to the position being played when probed. A book made from games starting with f5 also plays
+// after d3, c4 and e6, and after red moves first.
+type Book struct {
+	entries map[bookKey]map[int]float64
+}
+
+// A position of the book: the chips of the color whose turn it is and of its opponent
+type bookKey struct {
+	size int
+	own  Bitboard
+	opp  Bitboard
+}
+
+// A move of the book and its weight, which is the chance it's played relative to the position's other moves
+type BookMove struct {
+	Pos    int
+	Weight float64
+}
+
+// Initialize and return an empty book
+func NewBook() *Book {
+	return &Book{entries: make(map[bookKey]map[int]float64)}
+}
+
+// Get the number of positions in the book
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// The eight symmetries of the square, as the row and column the position in the given row and column
+// goes to, where last is the last row or column. Each symmetry is its own inverse, except the rotations
+// by a quarter turn (6 and 7), which are each other's inverse.
+var symmetries = [8]func(row, col, last int) (int, int){
+	func(row, col, last int) (int, int) { return row, col },
+	func(row, col, last int) (int, int) { return col, row },
+	func(row, col, last int) (int, int) { return last - row, last - col },
+	func(row, col, last int) (int, int) { return last - col, last - row },
+	func(row, col, last int) (int, int) { return row, last - col },
+	func(row, col, last int) (int, int) { return last - row, col },
+	func(row, col, last int) (int, int) { return col, last - row },
+	func(row, col, last int) (int, int) { return last - col, row },
+}
+
+// Get the symmetry that undoes the given one
+func inverseSymmetry(symmetry int) int {
+	switch symmetry {
+	case 6:
+		return 7
+	case 7:
+		return 6
+	}
+	return symmetry
+}
+
+// Get the position the given position goes to under the given symmetry
+func (g *Geometry) transformPos(pos, symmetry int) int {
+	row, col := symmetries[symmetry](pos/g.size, pos%g.size, g.size-1)
+	return g.Pos(row, col)
+}
+
+// Get the positions of the bitboard under the given symmetry
+func (g *Geometry) transform(b Bitboard, symmetry int) Bitboard {
+	var t Bitboard
+	for ; !b.IsEmpty(); b = b.withoutFirst() {
+		t = t.Or(bit(g.transformPos(b.First(), symmetry)))
+	}
+	return t
+}
+
+// Return whether bitboard b comes before bitboard c
+func (b Bitboard) less(c Bitboard) bool {
+	if b.hi != c.hi {
+		return b.hi < c.hi
+	}
+	return b.lo < c.lo
+}
+
+// Get the book position of the game and the symmetry that turns the game into it
+func bookKeyOf(r *Reversi) (bookKey, int) {
+	own, opp := r.ownAndOpp()
+	key := bookKey{size: r.size, own: own, opp: opp}
+	best := 0
+
+	for symmetry := 1; symmetry < len(symmetries); symmetry++ {
+		t := bookKey{size: r.size, own: r.transform(own, symmetry), opp: r.transform(opp, symmetry)}
+		if t.own.less(key.own) || (t.own == key.own && t.opp.less(key.opp)) {
+			key, best = t, symmetry
+		}
+	}
+
+	return key, best
+}
+
+// Add weight to the given position as a move of the game. Moves with no weight are never played, but
+// are kept to show that they were tried.
+func (b *Book) Add(r *Reversi, pos int, weight float64) error {
+	if !r.IsValidPosition(pos) {
+		return fmt.Errorf("%v is not a valid position", r.PosName(pos))
+	}
+
+	key, symmetry := bookKeyOf(r)
+	moves, ok := b.entries[key]
+	if !ok {
+		moves = make(map[int]float64)
+		b.entries[key] = moves
+	}
+	moves[r.transformPos(pos, symmetry)] += weight
+	return nil
+}
+
+// Add weight to every move of the given game. Games that did not start from the four starting chips
+// are left out, since they have no opening.
+func (b *Book) AddLine(game *Reversi, weight float64) error {
+	return b.addMoves(game, len(game.history), func(move Move) float64 {
+		return weight
+	})
+}
+
+// Add the given number of first moves of a finished game, each weighted by the points the color that
+// played it won: 1 for a win, 0.5 for a tie and 0 for a loss. Books of many games learn which moves do well.
+func (b *Book) AddGame(game *Reversi, moves int) error {
+	winner := game.CheckWin(true)
+	return b.addMoves(game, moves, func(move Move) float64 {
+		if winner == move.Color {
+			return 1
+		} else if winner == Tie {
+			return 0.5
+		}
+		return 0
+	})
+}
+
+// Add the given number of first moves of the game, passes not counted, with the weights of the given function
+func (b *Book) addMoves(game *Reversi, moves int, weight func(move Move) float64) error {
+	history := game.history
+	if !game.FromStart() || len(history) == 0 {
+		return nil
+	}
+
+	r, err := NewSize(game.size, history[0].Color)
+	if err != nil {
+		return err
+	}
+
+	for _, move := range history {
+		if moves <= 0 {
+			break
+		}
+		if move.Pos == NoMove {
+			r.Pass()
+			continue
+		}
+
+		if err := b.Add(r, move.Pos, weight(move)); err != nil {
+			return err
+		}
+		r.Play(move.Pos)
+		moves--
+	}
+	return nil
+}
+
+// Get the book moves of the game, with the highest weight first, or nil if the position is not in the book.
+// If the position is symmetric, like the four starting chips, the weight of a move is shared with the
+// moves it is symmetric to, so that the book plays all of them.
+func (b *Book) Moves(r *Reversi) []BookMove {
+	if b == nil {
+		return nil
+	}
+
+	key, symmetry := bookKeyOf(r)
+	inverse := inverseSymmetry(symmetry)
+
+	// The symmetries that leave the position as it is
+	var same []int
+	for s := range symmetries {
+		if r.transform(key.own, s) == key.own && r.transform(key.opp, s) == key.opp {
+			same = append(same, s)
+		}
+	}
+
+	weights := make(map[int]float64)
+	for pos, weight := range b.entries[key] {
+		images := make(map[int]bool)
+		for _, s := range same {
+			images[r.transformPos(pos, s)] = true
+		}
+		for image := range images {
+			weights[r.transformPos(image, inverse)] += weight / float64(len(images))
+		}
+	}
+
+	var moves []BookMove
+	for pos, weight := range weights {
+		moves = append(moves, BookMove{Pos: pos, Weight: weight})
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Weight != moves[j].Weight {
+			return moves[i].Weight > moves[j].Weight
+		}
+		return moves[i].Pos < moves[j].Pos
+	})
+	return moves
+}
+
+// Add the moves of another book to the book
+func (b *Book) Merge(other *Book) {
+	for key, moves := range other.entries {
+		if _, ok := b.entries[key]; !ok {
+			b.entries[key] = make(map[int]float64)
+		}
+		for pos, weight := range moves {
+			b.entries[key][pos] += weight
+		}
+	}
+}
+
+// Pick a book move of the game at random, each with a chance proportional to its weight. Returns false
+// if the position is not in the book or none of its moves has any weight.
+func (b *Book) Pick(r *Reversi, rng *rand.Rand) (int, bool) {
+	moves := b.Moves(r)
+
+	total := 0.0
+	for _, move := range moves {
+		total += move.Weight
+	}
+	if total <= 0 {
+		return NoMove, false
+	}
+
+	x := rng.Float64() * total
+	for _, move := range moves {
+		if x < move.Weight {
+			return move.Pos, true
+		}
+		x -= move.Weight
+	}
+
+	// Rounding can leave x just above the last weight
+	return moves[0].Pos, true
+}
+
+// Pick the move from the book if the game is in it, as the players do before searching
+func probeBook(r *Reversi, book *Book, rng *rand.Rand, stats *Stats) (int, bool) {
+	pos, ok := book.Pick(r, rng)
+	stats.Book = ok
+	return pos, ok
+}
+
+// Read the lines of a book and add them to the book. Each line is one of:
+//
+// A transcript and an optional weight, which is 1 if not given, such as "f5d6c3d3c4 10". Every move of
+// the transcript gets the weight, so lines that start the same way add up.
+// A position string, a move and an optional weight, such as "---...--- X d3 2", which gives the weight
+// to the move in that position.
+//
+// Everything after a # is a comment, and blank lines are skipped. Transcripts are played on the board of
+// the given size, and position strings on the board their length gives.
+func (b *Book) Read(reader io.Reader, size int) error {
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if comment := strings.IndexByte(text, '#'); comment >= 0 {
+			text = text[:comment]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := b.readLine(fields, size); err != nil {
+			return fmt.Errorf("invalid book line %v: %v", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Add a line of a book, split into its fields
+func (b *Book) readLine(fields []string, size int) error {
+	// Transcripts have row numbers, position strings don't have digits
+	if strings.ContainsAny(fields[0], "0123456789") {
+		if len(fields) > 2 {
+			return fmt.Errorf("expected a transcript and a weight")
+		}
+		weight, err := parseWeight(fields[1:])
+		if err != nil {
+			return err
+		}
+		r, err := ParseTranscript(fields[0], size)
+		if err != nil {
+			return err
+		}
+		return b.AddLine(r, weight)
+	}
+
+	if len(fields) < 3 || len(fields) > 4 {
+		return fmt.Errorf("expected a position string, a move and a weight")
+	}
+	weight, err := parseWeight(fields[3:])
+	if err != nil {
+		return err
+	}
+	r, err := ParsePosition(fields[0] + " " + fields[1])
+	if err != nil {
+		return err
+	}
+	pos, err := r.ParsePos(fields[2])
+	if err != nil {
+		return err
+	}
+	return b.Add(r, pos, weight)
+}
+
+// Get the weight of a book line, which is 1 if it has none
+func parseWeight(fields []string) (float64, error) {
+	if len(fields) == 0 {
+		return 1, nil
+	}
+	weight, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || weight < 0 {
+		return 0, fmt.Errorf("invalid weight %q", fields[0])
+	}
+	return weight, nil
+}
+
+// Write every move of the book as a position string, a move and a weight, which Read reads back
+func (b *Book) Write(w io.Writer) error {
+	var lines []string
+	for key, moves := range b.entries {
+		r := newGame(geometries[key.size], Blue)
+		r.setOwnAndOpp(key.own, key.opp)
+		r.rehash()
+		position := r.Position()
+		for pos, weight := range moves {
+			lines = append(lines, fmt.Sprintf("%v %v %v", position, r.PosName(pos), strconv.FormatFloat(weight, 'f', -1, 64)))
+		}
+	}
+	sort.Strings(lines)
+
+	writer := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// Get a copy of the built-in book of standard openings on the standard board
+func BuiltinBook() *Book {
+	b := NewBook()
+	if err := b.Read(strings.NewReader(builtinBook), DefaultSize); err != nil {
+		// The built-in book is checked by hand
+		panic(err)
+	}
+	return b
+}
+
+// Well-known openings, played by blue (black in standard Othello) from f5. Symmetry makes them play
+// from the other three first moves too.
+const builtinBook = `
+# Perpendicular openings
+f5d6c3d3c4 10         # Tiger
+f5d6c3d3c4f4c5b3c2 4  # Stephenson
+f5d6c5 3              # Cow
+f5d6c4 1
+
+# Diagonal openings
+f5f6e6f4c3 6          # Buffalo
+f5f6e6f4g5 4          # Heath
+f5f6e6f4e3 2
+`
@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Get the game with the chips of the given game under the given symmetry, and the same turn
+func transformed(r *Reversi, symmetry int) *Reversi {
+	t := newGame(r.Geometry, r.turn)
+	t.blue, t.red = r.transform(r.blue, symmetry), r.transform(r.red, symmetry)
+	t.rehash()
+	return t
+}
+
+// Get the weights of book moves by position
+func bookWeights(moves []BookMove) map[int]float64 {
+	weights := make(map[int]float64)
+	for _, move := range moves {
+		weights[move.Pos] = move.Weight
+	}
+	return weights
+}
+
+// Get a game from the start on a board of the given size, failing the test if there is none
+func newTestGame(t *testing.T, size int) *Reversi {
+	r, err := NewSize(size, Blue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Get a book of the given number of random games on a board of the given size, with random weights
+func randomBook(rng *rand.Rand, size, games int) (*Book, []*Reversi) {
+	b := NewBook()
+	var lines []*Reversi
+	for i := 0; i < games; i++ {
+		r := randomGame(rng, size, []int{Blue, Red}[i%2], 1+rng.Intn(12))
+		b.AddLine(r, float64(rng.Intn(4)))
+		lines = append(lines, r)
+	}
+	return b, lines
+}
+
+func TestBookMovesOfSymmetricPositions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{6, 8} {
+		b, lines := randomBook(rng, size, 40)
+		for _, line := range lines {
+			// Every position a move of the line was played from is in the book
+			for r := line.Copy(); ; {
+				move, ok := r.Undo()
+				if !ok {
+					break
+				} else if move.Pos == NoMove {
+					continue
+				}
+				moves := b.Moves(r)
+				if len(moves) == 0 {
+					t.Fatalf("%v is not in the book", r.Position())
+				}
+				for symmetry := range symmetries {
+					got := bookWeights(b.Moves(transformed(r, symmetry)))
+					if len(got) != len(moves) {
+						t.Fatalf("%v under symmetry %v has %v book moves, want %v", r.Position(), symmetry, len(got), len(moves))
+					}
+					for _, move := range moves {
+						pos := r.transformPos(move.Pos, symmetry)
+						if math.Abs(got[pos]-move.Weight) > 1e-9 {
+							t.Fatalf("%v under symmetry %v: weight of %v = %v, want the weight %v of %v", r.Position(), symmetry, r.PosName(pos), got[pos], move.Weight, r.PosName(move.Pos))
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// A position that isn't in the book has no moves
+	b, _ := randomBook(rng, 8, 10)
+	r, _ := ParseTranscript("f5f6e6f4e3c5c4e7", DefaultSize)
+	if moves := b.Moves(r); moves != nil {
+		t.Errorf("moves of a position out of the book = %v", moves)
+	}
+	if moves := (*Book)(nil).Moves(r); moves != nil {
+		t.Errorf("moves of no book = %v", moves)
+	}
+}
+
+func TestBookWriteRead(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	b, lines := randomBook(rng, 8, 30)
+	other, more := randomBook(rng, 6, 10)
+	b.Merge(other)
+	lines = append(lines, more...)
+
+	var written bytes.Buffer
+	if err := b.Write(&written); err != nil {
+		t.Fatal(err)
+	}
+	read := NewBook()
+	if err := read.Read(strings.NewReader(written.String()), DefaultSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Len() != b.Len() {
+		t.Errorf("the book read back has %v positions, want %v", read.Len(), b.Len())
+	}
+	var again bytes.Buffer
+	if err := read.Write(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != written.String() {
+		t.Errorf("the book read back writes\n%v\nwant\n%v", again.String(), written.String())
+	}
+	for _, line := range lines {
+		for r := line.Copy(); ; {
+			if _, ok := r.Undo(); !ok {
+				break
+			}
+			want, got := bookWeights(b.Moves(r)), bookWeights(read.Moves(r))
+			if len(got) != len(want) {
+				t.Fatalf("%v has %v moves in the book read back, want %v", r.Position(), len(got), len(want))
+			}
+			for pos, weight := range want {
+				if math.Abs(got[pos]-weight) > 1e-9 {
+					t.Fatalf("%v: weight of %v in the book read back = %v, want %v", r.Position(), r.PosName(pos), got[pos], weight)
+				}
+			}
+		}
+	}
+}
+
+func TestBookPick(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	r, _ := ParseTranscript("f5", DefaultSize)
+	d6, _ := r.ParsePos("d6")
+	f6, _ := r.ParsePos("f6")
+	f4, _ := r.ParsePos("f4")
+
+	// Moves without weight are never picked
+	b := NewBook()
+	b.Add(r, d6, 0)
+	b.Add(r, f6, 1)
+	b.Add(r, f4, 0)
+	for i := 0; i < 100; i++ {
+		if pos, ok := b.Pick(r, rng); !ok || pos != f6 {
+			t.Fatalf("picked %v, %v, want f6", r.PosName(pos), ok)
+		}
+	}
+
+	// Nor is anything from a position whose moves have no weight
+	b = NewBook()
+	b.Add(r, d6, 0)
+	if pos, ok := b.Pick(r, rng); ok {
+		t.Errorf("picked %v from moves without weight", r.PosName(pos))
+	}
+	if pos, ok := b.Pick(newTestGame(t, DefaultSize), rng); ok {
+		t.Errorf("picked %v from a position out of the book", r.PosName(pos))
+	}
+
+	// Moves are picked about as often as their weights say
+	b = NewBook()
+	b.Add(r, d6, 3)
+	b.Add(r, f6, 1)
+	picked := 0
+	for i := 0; i < 4000; i++ {
+		if pos, _ := b.Pick(r, rng); pos == d6 {
+			picked++
+		}
+	}
+	if picked < 2800 || picked > 3200 {
+		t.Errorf("picked d6 %v times of 4000, want about 3000", picked)
+	}
+}
+
+func TestBuiltinBook(t *testing.T) {
+	b := BuiltinBook()
+
+	// The four first moves are symmetric, so each gets a quarter of the weight
+	start := newTestGame(t, DefaultSize)
+	moves := b.Moves(start)
+	var names []string
+	for _, move := range moves {
+		names = append(names, start.PosName(move.Pos))
+	}
+	if strings.Join(names, " ") != "d3 c4 f5 e6" || moves[0].Weight != moves[3].Weight {
+		t.Errorf("book moves of the start = %v, want d3, c4, f5 and e6 with the same weight", moves)
+	}
+
+	// Every first move is answered the way f5 is, turned to it
+	f5, _ := ParseTranscript("f5", DefaultSize)
+	answers := b.Moves(f5)
+	if len(answers) != 2 || f5.PosName(answers[0].Pos) != "d6" || f5.PosName(answers[1].Pos) != "f6" {
+		t.Fatalf("book moves after f5 = %v, want d6 then f6", answers)
+	}
+	want := map[string][]string{"d3": {"c5", "c3"}, "c4": {"e3", "c3"}, "e6": {"f4", "f6"}}
+	for first, names := range want {
+		r, _ := ParseTranscript(first, DefaultSize)
+		moves := b.Moves(r)
+		if len(moves) != len(answers) {
+			t.Errorf("book moves after %v = %v, want %v", first, moves, names)
+			continue
+		}
+		for i, move := range moves {
+			if r.PosName(move.Pos) != names[i] || move.Weight != answers[i].Weight {
+				t.Errorf("book move %v after %v = %v with weight %v, want %v with weight %v", i+1, first, r.PosName(move.Pos), move.Weight, names[i], answers[i].Weight)
+			}
+		}
+	}
+
+	// Red moving first plays the same openings
+	red, _ := NewSize(DefaultSize, Red)
+	if moves := b.Moves(red); len(moves) != 4 {
+		t.Errorf("book moves of the start with red first = %v, want 4", moves)
+	}
+}
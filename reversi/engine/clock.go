@@ -0,0 +1,94 @@
+package engine
+
+import "time"
+
+// Time the AI may take to pick its next move when no other limit is set
+const DefaultMoveTime time.Duration = 10 * time.Second
+
+// Time settings of a game
+type TimeControl struct {
+	// Maximum time for a single move. If 0 and there is no game clock, DefaultMoveTime is used.
+	MoveTime time.Duration
+	// Total time each color has for the whole game. If 0, there is no game clock.
+	Clock time.Duration
+	// Time added to a color's clock after each of its moves
+	Increment time.Duration
+}
+
+// Tracks the remaining time of both colors and decides how much of it to spend on each move
+type Clock struct {
+	Control   TimeControl
+	remaining map[int]time.Duration
+}
+
+// Initialize and return a clock with the full game time for both colors
+func NewClock(control TimeControl) *Clock {
+	return &Clock{
+		Control:   control,
+		remaining: map[int]time.Duration{Blue: control.Clock, Red: control.Clock},
+	}
+}
+
+// Return whether the game has a clock, rather than only a time per move
+func (c *Clock) HasClock() bool {
+	return c.Control.Clock > 0
+}
+
+// Get the time the given color has left on its clock
+func (c *Clock) Remaining(color int) time.Duration {
+	return c.remaining[color]
+}
+
+// Set the time the given color has left on its clock, such as when another program keeps the time
+func (c *Clock) SetRemaining(color int, remaining time.Duration) {
+	c.remaining[color] = remaining
+}
+
+// Subtract the time spent on a move from the color's clock and add the increment
+func (c *Clock) Spend(color int, spent time.Duration) {
+	if !c.HasClock() {
+		return
+	}
+
+	remaining := c.remaining[color] - spent
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.remaining[color] = remaining + c.Control.Increment
+}
+
+// Get the time the color whose turn it is may spend on its next move.
+// With a game clock, the remaining time is shared evenly between the moves the color has left to play,
+// which is about half of the empty positions, keeping a small reserve.
+func (c *Clock) Budget(r *Reversi) time.Duration {
+	moveTime := c.Control.MoveTime
+
+	if !c.HasClock() {
+		if moveTime == 0 {
+			return DefaultMoveTime
+		}
+		return moveTime
+	}
+
+	remaining := c.remaining[r.Turn()]
+	reserve := remaining / 20
+
+	// Number of moves the color still has to play, at least one
+	empties := r.Empties()
+	movesLeft := (empties + 1) / 2
+	if movesLeft < 1 {
+		movesLeft = 1
+	}
+
+	budget := (remaining-reserve)/time.Duration(movesLeft) + c.Control.Increment
+
+	// Never spend the reserve, and respect the time per move if there is one
+	if budget > remaining-reserve {
+		budget = remaining - reserve
+	}
+	if moveTime > 0 && budget > moveTime {
+		budget = moveTime
+	}
+
+	return budget
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		control   TimeControl
+		size      int
+		remaining time.Duration
+		budget    time.Duration
+	}{
+		{"no clock or time per move", TimeControl{}, 8, 0, DefaultMoveTime},
+		{"time per move", TimeControl{MoveTime: 2 * time.Second}, 8, 0, 2 * time.Second},
+		// 60 empty positions leave 30 moves to share 57s between, keeping 3s
+		{"clock", TimeControl{Clock: time.Minute}, 8, time.Minute, 1900 * time.Millisecond},
+		{"clock and increment", TimeControl{Clock: time.Minute, Increment: 2 * time.Second}, 8, time.Minute, 3900 * time.Millisecond},
+		{"clock and a shorter time per move", TimeControl{Clock: time.Minute, MoveTime: time.Second}, 8, time.Minute, time.Second},
+		{"clock and a longer time per move", TimeControl{Clock: time.Minute, MoveTime: 5 * time.Second}, 8, time.Minute, 1900 * time.Millisecond},
+		// 12 empty positions leave 6 moves
+		{"clock on a small board", TimeControl{Clock: time.Minute}, 4, 12 * time.Second, 1900 * time.Millisecond},
+		{"clock partly spent", TimeControl{Clock: time.Minute}, 8, 6 * time.Second, 190 * time.Millisecond},
+		// The increment doesn't let a move spend the reserve
+		{"nearly spent clock and increment", TimeControl{Clock: time.Minute, Increment: time.Second}, 8, 20 * time.Millisecond, 19 * time.Millisecond},
+		{"spent clock", TimeControl{Clock: time.Minute, Increment: time.Second}, 8, 0, 0},
+	}
+
+	for _, test := range tests {
+		r, err := NewSize(test.size, Blue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewClock(test.control)
+		c.SetRemaining(Blue, test.remaining)
+		if budget := c.Budget(r); budget != test.budget {
+			t.Errorf("%v: budget = %v, want %v", test.name, budget, test.budget)
+		}
+	}
+}
+
+func TestClockBudgetOfTheLastMove(t *testing.T) {
+	// A game with one empty position left still has a move to play, and keeps the reserve
+	r, _ := NewSize(4, Blue)
+	for r.Empties() > 1 {
+		if moves := r.ValidPositions(); len(moves) > 0 {
+			r.Play(moves[0])
+		} else {
+			r.Pass()
+		}
+	}
+	c := NewClock(TimeControl{Clock: time.Minute})
+	if budget, want := c.Budget(r), 57*time.Second; budget != want {
+		t.Errorf("budget of the last move = %v, want %v", budget, want)
+	}
+}
+
+func TestClockSpend(t *testing.T) {
+	tests := []struct {
+		name      string
+		control   TimeControl
+		spent     time.Duration
+		remaining time.Duration
+	}{
+		{"no clock", TimeControl{MoveTime: time.Second}, 5 * time.Second, 0},
+		{"clock", TimeControl{Clock: time.Minute}, 5 * time.Second, 55 * time.Second},
+		{"clock and increment", TimeControl{Clock: time.Minute, Increment: 2 * time.Second}, 5 * time.Second, 57 * time.Second},
+		// Going over the clock doesn't take more time than there is, and still adds the increment
+		{"more than the clock", TimeControl{Clock: time.Minute, Increment: 2 * time.Second}, 2 * time.Minute, 2 * time.Second},
+	}
+
+	for _, test := range tests {
+		c := NewClock(test.control)
+		c.Spend(Red, test.spent)
+		if remaining := c.Remaining(Red); remaining != test.remaining {
+			t.Errorf("%v: remaining after spending %v = %v, want %v", test.name, test.spent, remaining, test.remaining)
+		}
+		if remaining := c.Remaining(Blue); remaining != test.control.Clock {
+			t.Errorf("%v: the other color's clock = %v, want %v", test.name, remaining, test.control.Clock)
+		}
+	}
+}
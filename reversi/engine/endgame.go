@@ -0,0 +1,212 @@
+package engine
+
+import "sort"
+
+// Number of empty positions at which the AI stops using playouts and solves the rest of the game exactly
+const DefaultEndgameEmpties int = 12
+
+// Below this number of empty positions, moves are only ordered by parity since
+// counting the opponent's moves costs more than it saves
+const orderByMobilityEmpties int = 6
+
+// Below this number of empty positions, the transposition table is not used since searching the
+// positions again costs less than looking them up
+const tableEmpties int = 8
+
+// State of a single endgame solve
+type endgameSearch struct {
+	*Geometry
+	// Exact scores and bounds of the positions solved
+	table *TranspositionTable
+	nodes int
+}
+
+// Get the number of empty positions
+func (r *Reversi) Empties() int {
+	return r.cells - r.blue.Or(r.red).Count()
+}
+
+// Get the positions that are in a quadrant with an odd number of empty positions.
+// Playing there first tends to leave the opponent the even regions and us the last move in each region.
+func (g *Geometry) oddQuadrants(empty Bitboard) Bitboard {
+	var odd Bitboard
+	for _, quadrant := range g.quadrants {
+		if empty.And(quadrant).Count()%2 == 1 {
+			odd = odd.Or(quadrant)
+		}
+	}
+	return odd
+}
+
+// A move and how promising it looks, used to search the best moves first
+type orderedMove struct {
+	pos      int
+	priority int
+}
+
+// Get the valid moves ordered best first: corners, then moves that leave the opponent
+// the fewest replies (when there are enough empty positions for it to pay off), then parity
+func (g *Geometry) orderMoves(own, opp, moves Bitboard, empties int) []orderedMove {
+	odd := g.oddQuadrants(g.all.AndNot(own.Or(opp)))
+	ordered := make([]orderedMove, 0, moves.Count())
+
+	for ; !moves.IsEmpty(); moves = moves.withoutFirst() {
+		pos := moves.First()
+		priority := 0
+
+		if g.corners.Has(pos) {
+			priority += 1000
+		}
+		if odd.Has(pos) {
+			priority += 10
+		}
+		if empties > orderByMobilityEmpties {
+			newOwn, newOpp := played(own, opp, g.flips(own, opp, pos), pos)
+			replies := g.validMoves(newOpp, newOwn)
+			priority -= 20 * replies.Count()
+		}
+
+		ordered = append(ordered, orderedMove{pos: pos, priority: priority})
+	}
+
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].priority > ordered[j].priority })
+	return ordered
+}
+
+// Initialize and return a solve of the game, with a transposition table unless it's too small to need one
+func newEndgameSearch(r *Reversi) *endgameSearch {
+	s := &endgameSearch{Geometry: r.Geometry}
+	if r.Empties() > tableEmpties {
+		s.table = NewTranspositionTable(DefaultTableEntries)
+	}
+	return s
+}
+
+// Negamax search with alpha-beta pruning of the final disc difference for own, where color is the color
+// whose turn it is and hash the hash of the position. passed is true if the opponent has just passed the turn.
+func (s *endgameSearch) solve(own, opp Bitboard, hash uint64, color, alpha, beta int, passed bool) int {
+	s.nodes += 1
+
+	empties := s.cells - own.Or(opp).Count()
+	moves := s.validMoves(own, opp)
+
+	if moves.IsEmpty() {
+		// If neither color can move, the game is over
+		if passed {
+			return own.Count() - opp.Count()
+		}
+		// Otherwise pass the turn
+		return -s.solve(opp, own, hash^zobristTurn, -color, -beta, -alpha, true)
+	}
+
+	// The position may have been solved by another order of moves, or at least bounded
+	useTable := s.table != nil && empties >= tableEmpties
+	first := NoMove
+	if useTable {
+		if entry, ok := s.table.Probe(hash, own, opp); ok {
+			if entry.Bound == ExactScore {
+				return entry.Score
+			} else if entry.Bound == LowerBound && entry.Score > alpha {
+				alpha = entry.Score
+			} else if entry.Bound == UpperBound && entry.Score < beta {
+				beta = entry.Score
+			}
+			if alpha >= beta {
+				return entry.Score
+			}
+			first = entry.Best
+		}
+	}
+
+	originalAlpha := alpha
+	best := NoMove
+	ordered := s.orderMoves(own, opp, moves, empties)
+
+	// Search the best move of the table first
+	for i, move := range ordered {
+		if move.pos == first {
+			copy(ordered[1:i+1], ordered[:i])
+			ordered[0] = move
+			break
+		}
+	}
+
+	for _, move := range ordered {
+		flipped := s.flips(own, opp, move.pos)
+		newOwn, newOpp := played(own, opp, flipped, move.pos)
+
+		// The hash is only needed where the table is used
+		newHash := hash
+		if useTable && empties > tableEmpties {
+			newHash ^= zobristTurn ^ moveHash(color, move.pos, flipped)
+		}
+		score := -s.solve(newOpp, newOwn, newHash, -color, -beta, -alpha, false)
+
+		if score > alpha {
+			alpha = score
+			best = move.pos
+			// The opponent would never allow this line
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+
+	if useTable {
+		bound := ExactScore
+		if alpha <= originalAlpha {
+			bound = UpperBound
+		} else if alpha >= beta {
+			bound = LowerBound
+		}
+		s.table.Store(hash, own, opp, TableEntry{Depth: empties, Score: alpha, Bound: bound, Best: best})
+	}
+
+	return alpha
+}
+
+// Solve the rest of the game with perfect play from both colors. Returns the best move for the
+// current turn (NoMove if there is none), the final disc difference from the point of view of
+// the current turn, and the number of positions searched.
+func (r *Reversi) Solve() (int, int, int) {
+	own, opp := r.ownAndOpp()
+	s := newEndgameSearch(r)
+	s.nodes = 1
+
+	moves := r.validMoves(own, opp)
+	if moves.IsEmpty() {
+		return NoMove, s.solve(own, opp, r.hash, r.turn, -r.cells, r.cells, false), s.nodes
+	}
+
+	bestPos := NoMove
+	alpha := -r.cells - 1
+
+	for _, move := range r.orderMoves(own, opp, moves, r.Empties()) {
+		flipped := r.flips(own, opp, move.pos)
+		newOwn, newOpp := played(own, opp, flipped, move.pos)
+		hash := r.hash ^ zobristTurn ^ moveHash(r.turn, move.pos, flipped)
+		score := -s.solve(newOpp, newOwn, hash, -r.turn, -r.cells, -alpha, false)
+
+		if score > alpha {
+			alpha = score
+			bestPos = move.pos
+		}
+	}
+
+	return bestPos, alpha, s.nodes
+}
+
+// Solve the endgame if there are at most the given number of empty positions, filling in the stats.
+// Returns the move and whether the game was solved.
+func solveEndgame(r *Reversi, endgameEmpties int, stats *Stats) (int, bool) {
+	if r.Empties() > endgameEmpties {
+		return NoMove, false
+	}
+
+	pos, score, nodes := r.Solve()
+	stats.Proven = true
+	stats.Score = score
+	stats.Nodes = nodes
+
+	return pos, true
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Get the final disc difference for own with perfect play from both colors, by searching every move without
+// pruning. passed is true if the opponent has just passed the turn.
+func negamax(g *Geometry, own, opp Bitboard, passed bool) int {
+	moves := g.validMoves(own, opp)
+	if moves.IsEmpty() {
+		if passed {
+			return own.Count() - opp.Count()
+		}
+		return -negamax(g, opp, own, true)
+	}
+
+	best := -g.Cells() - 1
+	for ; !moves.IsEmpty(); moves = moves.withoutFirst() {
+		pos := moves.First()
+		newOwn, newOpp := played(own, opp, g.flips(own, opp, pos), pos)
+		if score := -negamax(g, newOpp, newOwn, false); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// Get a game of random moves from the start with the given number of empty positions, or nil if the game
+// ended before
+func randomEndgame(rng *rand.Rand, size, empties int) *Reversi {
+	r, _ := NewSize(size, Blue)
+	for r.Empties() > empties {
+		if r.IsOver() {
+			return nil
+		}
+		if moves := r.ValidPositions(); len(moves) > 0 {
+			r.Play(moves[rng.Intn(len(moves))])
+		} else {
+			r.Pass()
+		}
+	}
+	return r
+}
+
+// Solve a game by searching every move without pruning
+func negamaxOf(r *Reversi) int {
+	own, opp := r.ownAndOpp()
+	return negamax(r.Geometry, own, opp, false)
+}
+
+// Solve a game with the given table, nil for none
+func solveWithTable(r *Reversi, table *TranspositionTable) int {
+	s := &endgameSearch{Geometry: r.Geometry, table: table}
+	own, opp := r.ownAndOpp()
+	return s.solve(own, opp, r.Hash(), r.Turn(), -r.Cells(), r.Cells(), false)
+}
+
+func TestSolveMatchesNegamax(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{6, 8} {
+		for solved := 0; solved < 8; {
+			r := randomEndgame(rng, size, 10)
+			if r == nil {
+				continue
+			}
+			solved++
+			position := r.Position()
+			want := negamaxOf(r)
+
+			pos, score, _ := r.Solve()
+			if score != want {
+				t.Errorf("%v: Solve scores %v, negamax %v", position, score, want)
+			}
+			// The best move must reach the score
+			if pos != NoMove {
+				r.Play(pos)
+				if after := -negamaxOf(r); after != want {
+					t.Errorf("%v: Solve plays %v, which scores %v rather than %v", position, r.PosName(pos), after, want)
+				}
+				r.Undo()
+			}
+
+			// A table that was filled by solving the game before gives the same score from its entries
+			tables := map[string]*TranspositionTable{
+				"no table":     nil,
+				"a table":      NewTranspositionTable(DefaultTableEntries),
+				"a tiny table": NewTranspositionTable(4),
+			}
+			for name, table := range tables {
+				for _, run := range []string{"", " again"} {
+					if score := solveWithTable(r, table); score != want {
+						t.Errorf("%v: solving with %v%v scores %v, negamax %v", position, name, run, score, want)
+					}
+				}
+				if table != nil && table.Hits == 0 {
+					t.Errorf("%v: solving again with %v found nothing in it", position, name)
+				}
+			}
+		}
+	}
+}
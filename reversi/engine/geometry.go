@@ -0,0 +1,127 @@
+package engine
+
+import "fmt"
+
+// Number of positions in a row or column of the standard board
+const DefaultSize int = 8
+
+// Smallest and largest board sizes. Sizes are even, so that the four starting chips are in the center,
+// and a board fits in the 128 bits of a Bitboard.
+const MinSize int = 4
+const MaxSize int = 10
+
+// The shape of a board of a given size: the masks the bitboard operations need and the heuristic tables
+type Geometry struct {
+	size  int
+	cells int
+	// Every position of the board
+	all Bitboard
+	// Every position but the ones on the left-most and right-most columns
+	notLeftColumn  Bitboard
+	notRightColumn Bitboard
+	// Used by MCT heuristic function: the corners are the best positions, the positions next to
+	// them are bad and the ones diagonally next to them are the worst
+	corners        Bitboard
+	badPositions   Bitboard
+	worstPositions Bitboard
+	// The four quadrants of the board, used for parity
+	quadrants [4]Bitboard
+	// The eight directions a line of chips can be captured in
+	dirs [numDirections]direction
+}
+
+// The geometry of every supported size, indexed by size
+var geometries [MaxSize + 1]*Geometry
+
+func init() {
+	for size := MinSize; size <= MaxSize; size += 2 {
+		geometries[size] = newGeometry(size)
+	}
+}
+
+// Compute the geometry of a board of the given size
+func newGeometry(size int) *Geometry {
+	g := &Geometry{size: size, cells: size * size}
+	last := size - 1
+	half := size / 2
+
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			pos := g.Pos(row, col)
+			g.all = g.all.Or(bit(pos))
+			if col != 0 {
+				g.notLeftColumn = g.notLeftColumn.Or(bit(pos))
+			}
+			if col != last {
+				g.notRightColumn = g.notRightColumn.Or(bit(pos))
+			}
+
+			quadrant := 2*(row/half) + col/half
+			g.quadrants[quadrant] = g.quadrants[quadrant].Or(bit(pos))
+		}
+	}
+
+	for _, row := range []int{0, last} {
+		for _, col := range []int{0, last} {
+			// The neighbors of a corner are one step towards the center
+			rowStep, colStep := 1, 1
+			if row == last {
+				rowStep = -1
+			}
+			if col == last {
+				colStep = -1
+			}
+
+			g.corners = g.corners.Or(bit(g.Pos(row, col)))
+			g.badPositions = g.badPositions.Or(BitboardOf(g.Pos(row+rowStep, col), g.Pos(row, col+colStep)))
+			g.worstPositions = g.worstPositions.Or(bit(g.Pos(row+rowStep, col+colStep)))
+		}
+	}
+
+	g.dirs = g.directions()
+
+	return g
+}
+
+// Get the geometry of a board of the given size
+func GeometryOf(size int) (*Geometry, error) {
+	if size < MinSize || size > MaxSize || size%2 != 0 {
+		return nil, fmt.Errorf("invalid board size %v, expected an even size from %v to %v", size, MinSize, MaxSize)
+	}
+	return geometries[size], nil
+}
+
+// Get the number of positions in a row or column
+func (g *Geometry) Size() int {
+	return g.size
+}
+
+// Get the number of positions of the board
+func (g *Geometry) Cells() int {
+	return g.cells
+}
+
+// Get the position in the given row and column, counting from 0 at the top left corner
+func (g *Geometry) Pos(row, col int) int {
+	return row*g.size + col
+}
+
+// Get the corners, the best positions to have
+func (g *Geometry) Corners() Bitboard {
+	return g.corners
+}
+
+// Get the positions next to the corners, which are bad to have while the corner is empty
+func (g *Geometry) BadPositions() Bitboard {
+	return g.badPositions
+}
+
+// Get the positions diagonally next to the corners, which are the worst to have while the corner is empty
+func (g *Geometry) WorstPositions() Bitboard {
+	return g.worstPositions
+}
+
+// Get the positions own can play
+func (g *Geometry) ValidMoves(own, opp Bitboard) Bitboard {
+	return g.validMoves(own, opp)
+}
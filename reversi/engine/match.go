@@ -0,0 +1,62 @@
+package engine
+
+import "time"
+
+// The outcome of a game played by PlayGame
+type GameResult struct {
+	// Blue, Red or Tie
+	Winner int
+	// Final number of chips of each color
+	BlueScore int
+	RedScore  int
+	// Number of positions played, passes not counted
+	Moves   int
+	Elapsed time.Duration
+	// Playouts run and time spent searching by each color, for the moves that needed playouts
+	Playouts    map[int]int
+	PlayoutTime map[int]time.Duration
+}
+
+// Get the number of playouts per second of the given color over the whole game
+func (g GameResult) PlayOutsPerSecond(color int) float64 {
+	if g.PlayoutTime[color] == 0 {
+		return 0
+	}
+	return float64(g.Playouts[color]) / g.PlayoutTime[color].Seconds()
+}
+
+// Play the game out between the given players, each move within the time the clock allows, and return
+// the result. The game is played on r, which holds the final position and its history afterwards.
+func PlayGame(r *Reversi, players map[int]Player, clock *Clock) GameResult {
+	result := GameResult{Playouts: make(map[int]int), PlayoutTime: make(map[int]time.Duration)}
+	startTime := time.Now()
+
+	for !r.IsOver() {
+		color := r.Turn()
+
+		moveStart := time.Now()
+		pos, stats := players[color].BestMove(r, clock.Budget(r))
+		clock.Spend(color, time.Since(moveStart))
+
+		// If the player has no moves to make, pass the turn
+		if pos == NoMove {
+			r.Pass()
+			continue
+		}
+
+		if stats.Playouts > 0 {
+			result.Playouts[color] += stats.Playouts
+			result.PlayoutTime[color] += stats.Elapsed
+		}
+
+		r.Play(pos)
+		result.Moves += 1
+	}
+
+	result.Elapsed = time.Since(startTime)
+	result.Winner = r.CheckWin(true)
+	result.BlueScore = r.Score(Blue)
+	result.RedScore = r.Score(Red)
+
+	return result
+}
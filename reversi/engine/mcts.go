@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default UCT exploration constant (sqrt(2))
+const DefaultExploration float64 = math.Sqrt2
+
+// Monte Carlo Tree Search using UCT to select which branch of the tree to explore next
+type MCTS struct {
+	// Weight given to rarely visited moves during selection
+	Exploration float64
+	// Picks positions during the simulation step
+	Policy Policy
+	// Number of iterations to run. If 0, Playouts iterations are run for each valid position,
+	// which is the budget of a FlatSearch with the same number of playouts.
+	Iterations int
+	// Number of iterations for each valid position, used if Iterations is 0
+	Playouts int
+	// Number of trees searched in parallel, each by its own goroutine. If 0, one per CPU core.
+	Workers int
+	// Solve the game exactly once there are at most this many empty positions
+	EndgameEmpties int
+	// Source of the random numbers of the expansions and playouts. The search is repeated exactly by the
+	// same generator in the same state, as long as the number of workers is the same and the time limit
+	// isn't reached.
+	Rand *rand.Rand
+	// Moves played without searching in the positions they have, or nil for no book
+	Book *Book
+	// Called with the progress of the search about every ProgressInterval, or nil
+	Report func(Progress)
+}
+
+// A position in the search tree
+type node struct {
+	// The position played to reach this node, NoMove for a pass
+	pos int
+	// The color that played pos
+	color    int
+	parent   *node
+	children []*node
+	// Positions not yet expanded into children
+	untried []int
+	visits  int
+	// Sum of results from the point of view of color: 1 for a win, 0.5 for a tie
+	wins float64
+}
+
+// Initialize and return a search using the given playout policy
+func NewMCTS(policy Policy) *MCTS {
+	return &MCTS{
+		Exploration:    DefaultExploration,
+		Policy:         policy,
+		Playouts:       Playouts,
+		EndgameEmpties: DefaultEndgameEmpties,
+		Rand:           NewRand(0),
+	}
+}
+
+// Create a node for the given game, reached by color playing pos
+func newNode(r *Reversi, pos, color int, parent *node) *node {
+	n := &node{pos: pos, color: color, parent: parent}
+
+	// A game that has ended has nothing left to expand
+	if r.CheckWin(false) != Ongoing {
+		return n
+	}
+
+	n.untried = r.ValidPositions()
+
+	// If there are no valid positions, the only move is to pass, unless the other player can't move either
+	if n.untried == nil {
+		r.SwitchTurns()
+		if r.ValidPositions() != nil {
+			n.untried = []int{NoMove}
+		}
+		r.SwitchTurns()
+	}
+
+	return n
+}
+
+// Play the given position, or pass the turn if it's NoMove
+func playOrPass(r *Reversi, pos int) {
+	if pos == NoMove {
+		r.SwitchTurns()
+	} else {
+		r.play(pos)
+	}
+}
+
+// Get the UCT value of a child node
+func (n *node) uct(exploration float64) float64 {
+	return n.wins/float64(n.visits) + exploration*math.Sqrt(math.Log(float64(n.parent.visits))/float64(n.visits))
+}
+
+// Get the child with the highest UCT value
+func (n *node) selectChild(exploration float64) *node {
+	var best *node
+	bestValue := math.Inf(-1)
+	for _, child := range n.children {
+		if value := child.uct(exploration); value > bestValue {
+			bestValue = value
+			best = child
+		}
+	}
+	return best
+}
+
+// Expand a random untried position into a new child node
+func (n *node) expand(r *Reversi, rng *rand.Rand) *node {
+	ind := getRandInt(rng, 0, len(n.untried))
+	pos := n.untried[ind]
+
+	// Remove the position from the untried list
+	n.untried[ind] = n.untried[len(n.untried)-1]
+	n.untried = n.untried[:len(n.untried)-1]
+
+	color := r.Turn()
+	playOrPass(r, pos)
+	child := newNode(r, pos, color, n)
+	n.children = append(n.children, child)
+	return child
+}
+
+// Add the result of a playout to every node from n up to the root
+func (n *node) backpropagate(result int) {
+	for ; n != nil; n = n.parent {
+		n.visits += 1
+		if result == n.color {
+			n.wins += 1
+		} else if result == Tie {
+			n.wins += 0.5
+		}
+	}
+}
+
+// Grow a search tree for the given game until the iterations run out or the time limit is reached,
+// giving the visits of the root moves to the tracker, if any, as the given worker
+func (m *MCTS) search(r *Reversi, rng *rand.Rand, iterations int, deadline time.Time, timeLimitExceeded *int32, tracker *progressTracker, worker int) (*node, int) {
+	root := newNode(r, NoMove, r.Turn()*-1, nil)
+	playOuts := 0
+
+	for ; iterations > 0; iterations-- {
+
+		// If the time limit has elapsed since we started the search, end early
+		if time.Now().After(deadline) {
+			atomic.StoreInt32(timeLimitExceeded, 1)
+			break
+		}
+
+		cpy := r.searchCopy()
+		n := root
+
+		// Selection: walk down fully expanded nodes
+		for len(n.untried) == 0 && len(n.children) > 0 {
+			n = n.selectChild(m.Exploration)
+			playOrPass(cpy, n.pos)
+		}
+
+		// Expansion: add one new child to the tree
+		if len(n.untried) > 0 {
+			n = n.expand(cpy, rng)
+		}
+
+		// Simulation: play the rest of the game out using the policy
+		result := DoPlayOut(cpy, m.Policy, rng)
+
+		// Backpropagation: update the statistics along the path
+		n.backpropagate(result)
+
+		playOuts += 1
+
+		if tracker != nil && playOuts%progressCheckInterval == 0 {
+			tracker.update(worker, func(visits []int, wins []float64) {
+				for ind, pos := range tracker.positions {
+					visits[ind], wins[ind] = 0, 0
+					for _, child := range root.children {
+						if child.pos == pos {
+							visits[ind], wins[ind] = child.visits, child.wins
+						}
+					}
+				}
+			})
+		}
+	}
+
+	return root, playOuts
+}
+
+// Return the best move for the current turn within the time limit, or NoMove if there is none.
+// Every worker grows its own tree (root parallelization) and the visits of the root moves are summed.
+func (m *MCTS) BestMove(r *Reversi, timeLimit time.Duration) (int, Stats) {
+
+	var stats Stats
+
+	positions := r.ValidPositions()
+
+	// If there are no valid positions
+	if positions == nil {
+		return NoMove, stats
+	}
+
+	// If the position is in the book, there is no need to search
+	if pos, ok := probeBook(r, m.Book, m.Rand, &stats); ok {
+		return pos, stats
+	}
+
+	startTime := time.Now()
+
+	// If the endgame is small enough, solve it instead
+	if pos, ok := solveEndgame(r, m.EndgameEmpties, &stats); ok {
+		stats.Elapsed = time.Since(startTime)
+		return pos, stats
+	}
+
+	// If there is only one valid position, there is nothing to search
+	if len(positions) == 1 {
+		return positions[0], stats
+	}
+
+	evaluations, stats := m.grow(r, positions, startTime, timeLimit)
+	return evaluations[0].Pos, stats
+}
+
+// Return the evaluations of the valid positions for the current turn, most visited first,
+// or nil if there are none
+func (m *MCTS) Analyze(r *Reversi, timeLimit time.Duration) ([]Evaluation, Stats) {
+	return analyze(r, m.EndgameEmpties, func(positions []int, startTime time.Time) ([]Evaluation, Stats) {
+		return m.grow(r, positions, startTime, timeLimit)
+	})
+}
+
+// Grow the search trees until the iterations run out or the time limit is reached, and return the
+// evaluations of the given positions, most visited first
+func (m *MCTS) grow(r *Reversi, positions []int, startTime time.Time, timeLimit time.Duration) ([]Evaluation, Stats) {
+
+	var stats Stats
+
+	iterations := m.Iterations
+	if iterations == 0 {
+		iterations = m.Playouts * len(positions)
+	}
+	deadline := startTime.Add(timeLimit)
+	workers := numWorkers(m.Workers)
+	rngs := workerRands(m.Rand, workers)
+	var timeLimitExceeded int32
+	tracker := newProgressTracker(m.Report, positions, workers, startTime, true)
+
+	roots := make([]*node, workers)
+	playOuts := make([]int, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		// The iterations are shared out evenly, so that each tree grows the same way every time
+		share := iterations / workers
+		if w < iterations%workers {
+			share += 1
+		}
+
+		go func(w int) {
+			defer wg.Done()
+			roots[w], playOuts[w] = m.search(r, rngs[w], share, deadline, &timeLimitExceeded, tracker, w)
+		}(w)
+	}
+	wg.Wait()
+
+	stats.Elapsed = time.Since(startTime)
+	stats.TimeLimitExceeded = timeLimitExceeded == 1
+
+	// Merge the visits and wins of the root moves of every tree
+	visits := make(map[int]int)
+	wins := make(map[int]float64)
+	for w := 0; w < workers; w++ {
+		stats.Playouts += playOuts[w]
+		for _, child := range roots[w].children {
+			visits[child.pos] += child.visits
+			wins[child.pos] += child.wins
+		}
+	}
+
+	evaluations := make([]Evaluation, len(positions))
+	for ind, pos := range positions {
+		rate := winRate(wins[pos], visits[pos])
+		evaluations[ind] = Evaluation{Pos: pos, Visits: visits[pos], WinRate: rate, Score: rate}
+	}
+
+	// The most visited move is the most reliable one
+	sort.SliceStable(evaluations, func(i, j int) bool { return evaluations[i].Visits > evaluations[j].Visits })
+
+	return evaluations, stats
+}
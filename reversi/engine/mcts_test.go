@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Get a search of the given number of iterations with one worker and a generator of the given seed, which
+// isn't 0, without the book and the endgame solver
+func newTestMCTS(iterations int, seed int64) *MCTS {
+	m := NewMCTS(HeuristicPos)
+	m.Iterations = iterations
+	m.Workers = 1
+	m.EndgameEmpties = 0
+	m.Rand = NewRand(seed)
+	return m
+}
+
+// Find a game of random moves from the start for which the given condition holds
+func findGame(t *testing.T, rng *rand.Rand, found func(r *Reversi) bool) *Reversi {
+	for game := 0; game < 1000; game++ {
+		r, _ := NewSize(DefaultSize, Blue)
+		for !r.IsOver() {
+			if found(r) {
+				return r
+			}
+			if moves := r.ValidPositions(); len(moves) > 0 {
+				r.Play(moves[rng.Intn(len(moves))])
+			} else {
+				r.Pass()
+			}
+		}
+	}
+	t.Fatal("no game found")
+	return nil
+}
+
+func TestMCTSForcedMove(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	r := findGame(t, rng, func(r *Reversi) bool {
+		return len(r.ValidPositions()) == 1 && r.Empties() > 20
+	})
+
+	pos, stats := newTestMCTS(1000, 1).BestMove(r, time.Hour)
+	if only := r.ValidPositions()[0]; pos != only {
+		t.Errorf("%v: plays %v, want the only valid position %v", r.Position(), r.PosName(pos), r.PosName(only))
+	}
+	if stats.Playouts != 0 {
+		t.Errorf("%v: ran %v playouts for the only valid position", r.Position(), stats.Playouts)
+	}
+}
+
+func TestMCTSPass(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// Without a valid position, the color passes
+	r := findGame(t, rng, func(r *Reversi) bool {
+		return r.Moves().IsEmpty() && !r.IsOver()
+	})
+	if pos, _ := newTestMCTS(1000, 1).BestMove(r, time.Hour); pos != NoMove {
+		t.Errorf("%v: plays %v, want a pass", r.Position(), r.PosName(pos))
+	}
+
+	// A move after which the opponent has to pass is followed by a pass in the tree, and the color
+	// moves again
+	var passing int
+	r = findGame(t, rng, func(r *Reversi) bool {
+		if r.Empties() < 6 {
+			return false
+		}
+		for _, pos := range r.ValidPositions() {
+			r.Play(pos)
+			pass := r.Moves().IsEmpty() && !r.IsOver()
+			r.Undo()
+			if pass {
+				passing = pos
+				return true
+			}
+		}
+		return false
+	})
+
+	m := newTestMCTS(0, 1)
+	var timeLimitExceeded int32
+	root, playOuts := m.search(r, m.Rand, 5000, time.Now().Add(time.Hour), &timeLimitExceeded, nil, 0)
+	if playOuts != 5000 {
+		t.Fatalf("%v: ran %v playouts, want 5000", r.Position(), playOuts)
+	}
+	for _, child := range root.children {
+		if child.pos != passing {
+			continue
+		}
+		if len(child.children) != 1 || child.children[0].pos != NoMove || child.children[0].color != -r.Turn() {
+			t.Fatalf("%v: after %v, the tree has %v moves rather than a pass of the opponent", r.Position(), r.PosName(passing), len(child.children))
+		}
+		pass := child.children[0]
+		if pass.visits != child.visits-1 || len(pass.children) == 0 || pass.children[0].color != r.Turn() {
+			t.Errorf("%v: the pass after %v has %v visits of %v and %v moves after it", r.Position(), r.PosName(passing), pass.visits, child.visits, len(pass.children))
+		}
+		return
+	}
+	t.Errorf("%v: %v was never searched", r.Position(), r.PosName(passing))
+}
+
+func TestMCTSPlaysMostVisitedMove(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for searched := 0; searched < 10; searched++ {
+		r := findGame(t, rng, func(r *Reversi) bool {
+			return len(r.ValidPositions()) >= 3 && rng.Intn(10) == 0
+		})
+
+		// The same seed searches the same way, so the move played is the first of the evaluations
+		const iterations = 3000
+		seed := int64(searched + 1)
+		evaluations, stats := newTestMCTS(iterations, seed).Analyze(r, time.Hour)
+		pos, _ := newTestMCTS(iterations, seed).BestMove(r, time.Hour)
+
+		visits := 0
+		for _, evaluation := range evaluations {
+			visits += evaluation.Visits
+			if evaluation.Visits > evaluations[0].Visits {
+				t.Errorf("%v: %v has %v visits, more than the %v of the first evaluation %v", r.Position(), r.PosName(evaluation.Pos), evaluation.Visits, evaluations[0].Visits, r.PosName(evaluations[0].Pos))
+			}
+		}
+		if pos != evaluations[0].Pos {
+			t.Errorf("%v: plays %v, want the most visited %v", r.Position(), r.PosName(pos), r.PosName(evaluations[0].Pos))
+		}
+		if visits != iterations || stats.Playouts != iterations || len(evaluations) != len(r.ValidPositions()) {
+			t.Errorf("%v: %v evaluations with %v visits and %v playouts, want %v with %v", r.Position(), len(evaluations), visits, stats.Playouts, len(r.ValidPositions()), iterations)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Picks the moves of one side of a game. FlatSearch, MCTS and AlphaBeta are players,
+// and so is anything else that can pick a move, such as a person at a terminal.
+type Player interface {
+	// Return the position to play for the current turn within the time limit, or NoMove if there is none
+	BestMove(r *Reversi, timeLimit time.Duration) (int, Stats)
+}
+
+// Settings shared by the players created by NewPlayer
+type PlayerConfig struct {
+	// Solve the game exactly once there are at most this many empty positions
+	EndgameEmpties int
+	// Number of playouts for each valid position of the playout-based searches. If 0, Playouts is used.
+	Playouts int
+	// Seed of the player's random numbers. If 0, a random seed is used.
+	Seed int64
+	// Number of workers of the playout-based searches. If 0, there is one per CPU core.
+	Workers int
+	// Opening book of the searching players, or nil for none
+	Book *Book
+}
+
+// Get the number of playouts for each valid position
+func (c PlayerConfig) playouts() int {
+	if c.Playouts > 0 {
+		return c.Playouts
+	}
+	return Playouts
+}
+
+// Plays a random position, picked by the same heuristics as the heuristic playouts, without searching
+type HeuristicPlayer struct {
+	Rand *rand.Rand
+}
+
+// Plays a random valid position
+type RandomPlayer struct {
+	Rand *rand.Rand
+}
+
+// Initialize and return a heuristic player
+func NewHeuristicPlayer() *HeuristicPlayer {
+	return &HeuristicPlayer{Rand: NewRand(0)}
+}
+
+// Return a position picked by the heuristics, or NoMove if there is none
+func (p *HeuristicPlayer) BestMove(r *Reversi, timeLimit time.Duration) (int, Stats) {
+	moves := r.Moves()
+	if moves.IsEmpty() {
+		return NoMove, Stats{}
+	}
+	return HeuristicPos(r.Geometry, p.Rand, moves), Stats{}
+}
+
+// Initialize and return a random player
+func NewRandomPlayer() *RandomPlayer {
+	return &RandomPlayer{Rand: NewRand(0)}
+}
+
+// Return a random valid position, or NoMove if there is none
+func (p *RandomPlayer) BestMove(r *Reversi, timeLimit time.Duration) (int, Stats) {
+	moves := r.Moves()
+	if moves.IsEmpty() {
+		return NoMove, Stats{}
+	}
+	return RandPos(r.Geometry, p.Rand, moves), Stats{}
+}
+
+// Create a player for the given configuration
+type playerFactory func(config PlayerConfig) Player
+
+// Players that can be created by name
+var playerFactories = map[string]playerFactory{
+	"mcts": func(config PlayerConfig) Player {
+		m := NewMCTS(HeuristicPos)
+		m.EndgameEmpties = config.EndgameEmpties
+		m.Playouts = config.playouts()
+		m.Workers = config.Workers
+		m.Rand = NewRand(config.Seed)
+		m.Book = config.Book
+		return m
+	},
+	"flat": func(config PlayerConfig) Player {
+		f := NewFlatSearch(HeuristicPos)
+		f.EndgameEmpties = config.EndgameEmpties
+		f.Playouts = config.playouts()
+		f.Workers = config.Workers
+		f.Rand = NewRand(config.Seed)
+		f.Book = config.Book
+		return f
+	},
+	"alphabeta": func(config PlayerConfig) Player {
+		a := NewAlphaBeta(HeuristicEval)
+		a.EndgameEmpties = config.EndgameEmpties
+		a.Rand = NewRand(config.Seed)
+		a.Book = config.Book
+		return a
+	},
+	"heuristic": func(config PlayerConfig) Player {
+		return &HeuristicPlayer{Rand: NewRand(config.Seed)}
+	},
+	"random": func(config PlayerConfig) Player {
+		return &RandomPlayer{Rand: NewRand(config.Seed)}
+	},
+}
+
+// Get the names of the players that NewPlayer can create, in alphabetical order
+func PlayerNames() []string {
+	var names []string
+	for name := range playerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returned by NewPlayer for a name that is not one of PlayerNames
+var ErrUnknownPlayer = errors.New("unknown player")
+
+// Create the player described by the given spec: the name of a player, optionally followed by a colon
+// and comma-separated settings that override the configuration, such as "flat:playouts=1000,loss=-5".
+// The players are:
+// mcts: MCTS with heuristic playouts
+// flat: flat Monte Carlo search with heuristic playouts
+// alphabeta: alpha-beta search with the heuristic evaluation
+// heuristic: plays the heuristic playout policy without searching
+// random: plays random valid positions
+//
+// The settings are:
+// playouts, endgame, workers and policy (heuristic or random) for mcts and flat,
+// exploration and iterations for mcts,
+// win, loss and tie (the scores of the playout results) for flat,
+// depth, endgame, eval (heuristic or chips) and table (the number of entries of the transposition table,
+// 0 for none) for alphabeta
+func NewPlayer(spec string, config PlayerConfig) (Player, error) {
+	name, settings := SplitPlayerSpec(spec)
+
+	factory, ok := playerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %v", ErrUnknownPlayer, name, PlayerNames())
+	}
+
+	player := factory(config)
+	for _, setting := range settings {
+		keyValue := strings.SplitN(setting, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("invalid setting %q of player %q, expected <setting>=<value>", setting, spec)
+		}
+		if err := configure(player, keyValue[0], keyValue[1]); err != nil {
+			return nil, fmt.Errorf("invalid setting %q of player %q: %v", setting, spec, err)
+		}
+	}
+
+	return player, nil
+}
+
+// Get the name of the player of a player spec and its settings
+func SplitPlayerSpec(spec string) (string, []string) {
+	nameSettings := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+	if len(nameSettings) == 1 || nameSettings[1] == "" {
+		return nameSettings[0], nil
+	}
+	return nameSettings[0], strings.Split(nameSettings[1], ",")
+}
+
+// Change a setting of a player
+func configure(player Player, key, value string) error {
+	switch p := player.(type) {
+	case *MCTS:
+		switch key {
+		case "exploration":
+			return parseFloat(value, &p.Exploration)
+		case "iterations":
+			return parseInt(value, &p.Iterations)
+		case "playouts":
+			return parseInt(value, &p.Playouts)
+		case "workers":
+			return parseInt(value, &p.Workers)
+		case "endgame":
+			return parseInt(value, &p.EndgameEmpties)
+		case "policy":
+			return parsePolicy(value, &p.Policy)
+		}
+	case *FlatSearch:
+		switch key {
+		case "playouts":
+			return parseInt(value, &p.Playouts)
+		case "workers":
+			return parseInt(value, &p.Workers)
+		case "endgame":
+			return parseInt(value, &p.EndgameEmpties)
+		case "policy":
+			return parsePolicy(value, &p.Policy)
+		case "win":
+			return parseInt(value, &p.WinScore)
+		case "loss":
+			return parseInt(value, &p.LossScore)
+		case "tie":
+			return parseInt(value, &p.TieScore)
+		}
+	case *AlphaBeta:
+		switch key {
+		case "depth":
+			return parseInt(value, &p.MaxDepth)
+		case "endgame":
+			return parseInt(value, &p.EndgameEmpties)
+		case "table":
+			return parseTable(value, &p.Table)
+		case "eval":
+			switch value {
+			case "heuristic":
+				p.Evaluator = HeuristicEval
+				return nil
+			case "chips":
+				p.Evaluator = ChipEval
+				return nil
+			}
+			return fmt.Errorf("unknown evaluation %q, expected heuristic or chips", value)
+		}
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// Parse an integer setting
+func parseInt(value string, setting *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", value)
+	}
+	*setting = n
+	return nil
+}
+
+// Parse a number setting
+func parseFloat(value string, setting *float64) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	*setting = f
+	return nil
+}
+
+// Parse a transposition table setting: its number of entries, or 0 for no table
+func parseTable(value string, setting **TranspositionTable) error {
+	var entries int
+	if err := parseInt(value, &entries); err != nil {
+		return err
+	}
+	if entries < 0 {
+		return fmt.Errorf("the number of entries can't be negative")
+	}
+
+	*setting = nil
+	if entries > 0 {
+		*setting = NewTranspositionTable(entries)
+	}
+	return nil
+}
+
+// Parse a playout policy setting
+func parsePolicy(value string, setting *Policy) error {
+	switch value {
+	case "heuristic":
+		*setting = HeuristicPos
+		return nil
+	case "random":
+		*setting = RandPos
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q, expected heuristic or random", value)
+}
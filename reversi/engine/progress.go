@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// Time between the progress reports of a search
+const ProgressInterval time.Duration = 250 * time.Millisecond
+
+// Number of playouts a worker runs between checks of whether the progress is due
+const progressCheckInterval int = 256
+
+// How a search is going, reported while it runs
+type Progress struct {
+	// Best position found so far, or NoMove if there is none yet
+	Best int
+	// Playouts run and positions searched so far
+	Playouts int
+	Nodes    int
+	// Depth in moves completed by the alpha-beta search
+	Depth   int
+	Elapsed time.Duration
+}
+
+// Set the function the given player calls with the progress of its searches, about every ProgressInterval
+// for the playout-based searches and at every depth for alpha-beta, or stop the reports with nil. Return
+// whether the player can report its progress.
+func SetReport(player Player, report func(Progress)) bool {
+	switch p := player.(type) {
+	case *MCTS:
+		p.Report = report
+	case *FlatSearch:
+		p.Report = report
+	case *AlphaBeta:
+		p.Report = report
+	default:
+		return false
+	}
+	return true
+}
+
+// Gathers the playouts of the workers of a playout-based search and reports the progress of the search
+type progressTracker struct {
+	mu        sync.Mutex
+	report    func(Progress)
+	positions []int
+	// Playouts of each position and their total score, per worker
+	visits [][]int
+	scores [][]float64
+	// Whether the best position is the most visited one, rather than the one with the best average score
+	byVisits   bool
+	startTime  time.Time
+	lastReport time.Time
+}
+
+// Initialize and return a tracker of the given positions searched by the given number of workers, or nil
+// if there is nothing to report to
+func newProgressTracker(report func(Progress), positions []int, workers int, startTime time.Time, byVisits bool) *progressTracker {
+	if report == nil {
+		return nil
+	}
+	t := &progressTracker{
+		report:     report,
+		positions:  positions,
+		visits:     make([][]int, workers),
+		scores:     make([][]float64, workers),
+		byVisits:   byVisits,
+		startTime:  startTime,
+		lastReport: startTime,
+	}
+	for w := 0; w < workers; w++ {
+		t.visits[w] = make([]int, len(positions))
+		t.scores[w] = make([]float64, len(positions))
+	}
+	return t
+}
+
+// Replace the playouts of a worker with the ones fill writes, by position index, and report the progress
+// if it's due. Reports are made by one worker at a time.
+func (t *progressTracker) update(worker int, fill func(visits []int, scores []float64)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fill(t.visits[worker], t.scores[worker])
+	if time.Since(t.lastReport) < ProgressInterval {
+		return
+	}
+	t.lastReport = time.Now()
+
+	progress := Progress{Best: NoMove, Elapsed: time.Since(t.startTime)}
+	bestVisits, bestScore := 0, 0.0
+	for ind, pos := range t.positions {
+		visits, score := 0, 0.0
+		for w := range t.visits {
+			visits += t.visits[w][ind]
+			score += t.scores[w][ind]
+		}
+		progress.Playouts += visits
+		if visits == 0 {
+			continue
+		}
+
+		if t.byVisits && visits > bestVisits || !t.byVisits && (progress.Best == NoMove || score/float64(visits) > bestScore) {
+			progress.Best, bestVisits, bestScore = pos, visits, score/float64(visits)
+		}
+	}
+	t.report(progress)
+}
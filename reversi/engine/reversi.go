@@ -0,0 +1,323 @@
+// Package engine implements the rules of Reversi and the search-based AI shared by the
+// reversi and reversiSimulation commands.
+package engine
+
+// Initializing constants
+const Blue int = 1
+const Red int = -1
+const Tie int = 0
+const Empty int = 0
+
+// Returned by CheckWin while the game is still on-going
+const Ongoing int = 2
+
+// Returned when a side has no position to play
+const NoMove int = -1
+
+// A move that has been played, with everything needed to take it back exactly
+type Move struct {
+	// Position played, or NoMove for a pass
+	Pos int
+	// Color that played the move
+	Color int
+	// Chips of the opposite color that were flipped
+	Flipped Bitboard
+}
+
+// Game state: the board, the chips of each color, the color whose turn it is and the moves played so far
+type Reversi struct {
+	*Geometry
+	blue Bitboard
+	red  Bitboard
+	turn int
+	// Zobrist hash of the chips and the turn, kept up to date by every change to them
+	hash uint64
+	// Moves played with Play and Pass since the start of the game
+	history []Move
+	// Moves taken back with Undo, most recent last, until another move is played
+	undone []Move
+	// Whether the game started from a given position rather than the four starting chips
+	customStart bool
+}
+
+// Initialize and return a new game on the standard board with the four starting chips, where the given
+// color moves first
+func New(turn int) *Reversi {
+	return newGame(geometries[DefaultSize], turn)
+}
+
+// Initialize and return a new game on a board of the given size with the four starting chips, where the
+// given color moves first
+func NewSize(size, turn int) (*Reversi, error) {
+	g, err := GeometryOf(size)
+	if err != nil {
+		return nil, err
+	}
+	return newGame(g, turn), nil
+}
+
+// Initialize and return a new game on the given board with the four starting chips in its center
+func newGame(g *Geometry, turn int) *Reversi {
+
+	// Create new game
+	r := &Reversi{Geometry: g}
+
+	// Set initial four chips
+	center := g.size / 2
+	r.red = BitboardOf(g.Pos(center-1, center-1), g.Pos(center, center))
+	r.blue = BitboardOf(g.Pos(center-1, center), g.Pos(center, center-1))
+
+	r.turn = turn
+	r.rehash()
+
+	return r
+}
+
+// Get the color whose turn it is
+func (r *Reversi) Turn() int {
+	return r.turn
+}
+
+// Set the color whose turn it is
+func (r *Reversi) SetTurn(color int) {
+	r.hash ^= turnKey(r.turn) ^ turnKey(color)
+	r.turn = color
+}
+
+// Pass the turn to the other color
+func (r *Reversi) SwitchTurns() {
+	r.SetTurn(r.turn * -1)
+}
+
+// Compute the hash again from the chips and the turn, after they were set directly
+func (r *Reversi) rehash() {
+	r.hash = hashOf(r.blue, r.red, r.turn)
+}
+
+// Get the chips of the given color
+func (r *Reversi) Chips(color int) Bitboard {
+	if color == Blue {
+		return r.blue
+	}
+	return r.red
+}
+
+// Get the chips of the current color and of the opposite color
+func (r *Reversi) ownAndOpp() (Bitboard, Bitboard) {
+	if r.turn == Blue {
+		return r.blue, r.red
+	}
+	return r.red, r.blue
+}
+
+// Get the chip at the given position (Blue, Red or Empty)
+func (r *Reversi) At(pos int) int {
+	if r.blue.Has(pos) {
+		return Blue
+	} else if r.red.Has(pos) {
+		return Red
+	}
+	return Empty
+}
+
+// Get the board cells
+func (r *Reversi) Board() []int {
+	board := make([]int, r.cells)
+	for pos := range board {
+		board[pos] = r.At(pos)
+	}
+	return board
+}
+
+// Get a deep copy of the current game
+func (r *Reversi) Copy() *Reversi {
+	cpy := *r
+	cpy.history = append([]Move(nil), r.history...)
+	cpy.undone = append([]Move(nil), r.undone...)
+	return &cpy
+}
+
+// Get a copy of the chips and turn only, for searches that play many moves they don't need to record
+func (r *Reversi) searchCopy() *Reversi {
+	return &Reversi{Geometry: r.Geometry, blue: r.blue, red: r.red, turn: r.turn, hash: r.hash}
+}
+
+// Get the positions played since the start of the game, without the passes
+func (r *Reversi) History() []int {
+	var history []int
+	for _, move := range r.history {
+		if move.Pos != NoMove {
+			history = append(history, move.Pos)
+		}
+	}
+	return history
+}
+
+// Get the moves played since the start of the game, including the passes
+func (r *Reversi) PlayedMoves() []Move {
+	return append([]Move(nil), r.history...)
+}
+
+// Return whether the game started from the four starting chips, so its history is a full transcript
+func (r *Reversi) FromStart() bool {
+	return !r.customStart
+}
+
+// Get the score of a given color
+func (r *Reversi) Score(color int) int {
+	return r.Chips(color).Count()
+}
+
+// Determine who won based on given scores
+func DetermineWinner(blueScore, redScore int) int {
+	if blueScore > redScore {
+		// If blue has won
+		return Blue
+	} else if blueScore < redScore {
+		// If red has won
+		return Red
+	} else {
+		// If tie
+		return Tie
+	}
+}
+
+// If someone has won.
+// -1: red has won
+// 0: it's a tie
+// 1: blue has won
+// 2: nobody has won yet
+func (r *Reversi) CheckWin(forceWin bool) int {
+
+	// Get scores for each color
+	blueScore := r.Score(Blue)
+	redScore := r.Score(Red)
+
+	// If there are no empty positions or the caller of this function knows that neither players can make a move, then they can opt to end it early.
+	if blueScore+redScore == r.cells || forceWin {
+		return DetermineWinner(blueScore, redScore)
+	}
+
+	// If the game is still on-going
+	return Ongoing
+}
+
+// Return whether neither color has a position left to play
+func (r *Reversi) IsOver() bool {
+	return r.validMoves(r.blue, r.red).IsEmpty() && r.validMoves(r.red, r.blue).IsEmpty()
+}
+
+// Get the positions the current color can play
+func (r *Reversi) Moves() Bitboard {
+	own, opp := r.ownAndOpp()
+	return r.validMoves(own, opp)
+}
+
+// Check if the a chip can be placed in given position
+func (r *Reversi) IsValidPosition(pos int) bool {
+
+	// If position is out of bounds
+	if pos < 0 || pos >= r.cells {
+		return false
+	}
+
+	return r.Moves().Has(pos)
+}
+
+// Return slice of valid positions for current turn
+func (r *Reversi) ValidPositions() []int {
+	return r.Moves().Positions()
+}
+
+// Set the given position to the current color chip, and return the chips that were flipped
+func (r *Reversi) SetChip(pos int) Bitboard {
+
+	// If position is not empty
+	if r.blue.Or(r.red).Has(pos) {
+		return Bitboard{}
+	}
+
+	// Flip every direction in which chips of the opposite color are sandwiched
+	// between the new chip and another chip of the current color.
+	own, opp := r.ownAndOpp()
+	flipped := r.flips(own, opp, pos)
+	r.setOwnAndOpp(played(own, opp, flipped, pos))
+	r.hash ^= moveHash(r.turn, pos, flipped)
+	return flipped
+}
+
+// Set the chips of the current color and of the opposite color
+func (r *Reversi) setOwnAndOpp(own, opp Bitboard) {
+	if r.turn == Blue {
+		r.blue, r.red = own, opp
+	} else {
+		r.red, r.blue = own, opp
+	}
+}
+
+// Play the given position for the current color, record it and pass the turn.
+// Returns the move, which Undo can take back.
+func (r *Reversi) Play(pos int) Move {
+	move := Move{Pos: pos, Color: r.turn, Flipped: r.SetChip(pos)}
+	r.SwitchTurns()
+	r.record(move)
+	return move
+}
+
+// Pass the turn of the current color and record it
+func (r *Reversi) Pass() Move {
+	move := Move{Pos: NoMove, Color: r.turn}
+	r.SwitchTurns()
+	r.record(move)
+	return move
+}
+
+// Record a move played, which replaces the moves that were taken back
+func (r *Reversi) record(move Move) {
+	r.history = append(r.history, move)
+	r.undone = nil
+}
+
+// Play the given position for the current color and pass the turn, without recording it
+func (r *Reversi) play(pos int) {
+	r.SetChip(pos)
+	r.SwitchTurns()
+}
+
+// Take back the last move played, and return it and whether there was one to take back
+func (r *Reversi) Undo() (Move, bool) {
+	if len(r.history) == 0 {
+		return Move{}, false
+	}
+
+	move := r.history[len(r.history)-1]
+	r.history = r.history[:len(r.history)-1]
+
+	r.SetTurn(move.Color)
+	if move.Pos != NoMove {
+		own, opp := r.ownAndOpp()
+		r.setOwnAndOpp(own.AndNot(move.Flipped.Or(bit(move.Pos))), opp.Or(move.Flipped))
+		r.hash ^= moveHash(move.Color, move.Pos, move.Flipped)
+	}
+
+	r.undone = append(r.undone, move)
+	return move, true
+}
+
+// Play again the last move taken back by Undo, and return it and whether there was one to play
+func (r *Reversi) Redo() (Move, bool) {
+	if len(r.undone) == 0 {
+		return Move{}, false
+	}
+
+	move := r.undone[len(r.undone)-1]
+	r.undone = r.undone[:len(r.undone)-1]
+
+	if move.Pos != NoMove {
+		r.SetChip(move.Pos)
+	}
+	r.SwitchTurns()
+	r.history = append(r.history, move)
+
+	return move, true
+}
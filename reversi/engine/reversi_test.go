@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// A game's chips, turn and hash, to check that undoing a move brings them back
+type snapshot struct {
+	blue, red Bitboard
+	turn      int
+	hash      uint64
+}
+
+func snapshotOf(r *Reversi) snapshot {
+	return snapshot{blue: r.blue, red: r.red, turn: r.turn, hash: r.hash}
+}
+
+// Check that the hash kept up to date by the moves is the one computed again from the chips and the turn
+func checkHash(t *testing.T, r *Reversi, after string) {
+	t.Helper()
+	rehashed := *r
+	rehashed.rehash()
+	if r.Hash() != rehashed.hash {
+		t.Fatalf("hash after %v = %x, computed again %x", after, r.Hash(), rehashed.hash)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for size := MinSize; size <= MaxSize; size += 2 {
+		for game := 0; game < 20; game++ {
+			r, err := NewSize(size, Blue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := func(what string, move int) string {
+				return fmt.Sprintf("%vx%v game %v: %v %v", size, size, game, what, move)
+			}
+
+			// Play random moves to the end
+			var snapshots []snapshot
+			for !r.IsOver() {
+				snapshots = append(snapshots, snapshotOf(r))
+				if moves := r.ValidPositions(); len(moves) > 0 {
+					r.Play(moves[rng.Intn(len(moves))])
+					checkHash(t, r, name("playing move", len(snapshots)))
+				} else {
+					r.Pass()
+					checkHash(t, r, name("passing at move", len(snapshots)))
+				}
+				if !r.blue.And(r.red).IsEmpty() {
+					t.Fatalf("%v: a position has both colors", name("move", len(snapshots)))
+				}
+			}
+			end := snapshotOf(r)
+
+			// Take every move back, and play them all again
+			for i := len(snapshots) - 1; i >= 0; i-- {
+				if _, ok := r.Undo(); !ok {
+					t.Fatalf("%v: can't undo it", name("move", i+1))
+				}
+				checkHash(t, r, name("undoing move", i+1))
+				if got := snapshotOf(r); got != snapshots[i] {
+					t.Fatalf("%v gives %+v, want %+v", name("undoing move", i+1), got, snapshots[i])
+				}
+			}
+			if _, ok := r.Undo(); ok {
+				t.Fatalf("%vx%v game %v: undid a move before the start", size, size, game)
+			}
+			for i := range snapshots {
+				if _, ok := r.Redo(); !ok {
+					t.Fatalf("%v: can't redo it", name("move", i+1))
+				}
+				checkHash(t, r, name("redoing move", i+1))
+			}
+			if got := snapshotOf(r); got != end {
+				t.Fatalf("%vx%v game %v: redoing every move gives %+v, want %+v", size, size, game, got, end)
+			}
+			if _, ok := r.Redo(); ok {
+				t.Fatalf("%vx%v game %v: redid a move that wasn't taken back", size, size, game)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Characters of the position string. Blue plays the black chips of standard Othello notation (X),
+// which is why its starting chips are on d5 and e4, and red plays the white chips (O).
+const blueChar byte = 'X'
+const redChar byte = 'O'
+const emptyChar byte = '-'
+
+// Get the name of a position in standard Othello coordinates: a column letter from a followed by a row
+// number from 1, so on the standard board position 0 is "a1" and position 63 is "h8"
+func (g *Geometry) PosName(pos int) string {
+	return fmt.Sprintf("%c%d", 'a'+pos%g.size, pos/g.size+1)
+}
+
+// Get the position named by a coordinate such as "d3" or "D3"
+func (g *Geometry) ParsePos(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 2 || name[0] < 'a' || name[0] >= 'a'+byte(g.size) {
+		return NoMove, fmt.Errorf("invalid coordinate %q", name)
+	}
+
+	row, err := strconv.Atoi(name[1:])
+	if err != nil || row < 1 || row > g.size || name[1] == '0' {
+		return NoMove, fmt.Errorf("invalid coordinate %q", name)
+	}
+	return g.Pos(row-1, int(name[0]-'a')), nil
+}
+
+// Get the transcript of the positions played since the start, such as "f5d6c3"
+func (r *Reversi) Transcript() string {
+	var transcript strings.Builder
+	for _, pos := range r.History() {
+		transcript.WriteString(r.PosName(pos))
+	}
+	return transcript.String()
+}
+
+// Split a transcript into its coordinates, each a letter followed by a row number
+func splitTranscript(transcript string) []string {
+	var coordinates []string
+	start := 0
+	for i := 1; i <= len(transcript); i++ {
+		if i == len(transcript) || (transcript[i] < '0' || transcript[i] > '9') {
+			coordinates = append(coordinates, transcript[start:i])
+			start = i
+		}
+	}
+	return coordinates
+}
+
+// Get the game described by a transcript, played from the four starting chips on a board of the given
+// size. The color that moved first is found from the first position, since the two colors' first moves
+// are different. Passes are not part of transcripts, a color without valid positions passes its turn.
+func ParseTranscript(transcript string, size int) (*Reversi, error) {
+	transcript = strings.Join(strings.Fields(transcript), "")
+
+	r, err := NewSize(size, Blue)
+	if err != nil {
+		return nil, err
+	}
+
+	if transcript == "" {
+		return r, nil
+	}
+
+	for i, name := range splitTranscript(transcript) {
+		pos, err := r.ParsePos(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// The first position decides which color moved first
+		if i == 0 && !r.IsValidPosition(pos) {
+			r.SetTurn(Red)
+		}
+
+		// If the current color can't move, it passes
+		if r.Moves().IsEmpty() {
+			r.Pass()
+		}
+
+		if !r.IsValidPosition(pos) {
+			return nil, fmt.Errorf("invalid transcript: %v is not a valid position for move %v", r.PosName(pos), i+1)
+		}
+		r.Play(pos)
+	}
+
+	// If the next color can't move, it passes
+	if r.Moves().IsEmpty() && !r.IsOver() {
+		r.Pass()
+	}
+
+	return r, nil
+}
+
+// Get the position string of the board and the color whose turn it is: one character per position,
+// from a1 to h8 on the standard board, X for blue, O for red and - for empty, then a space and X or O
+// for the turn
+func (r *Reversi) Position() string {
+	var position strings.Builder
+	for pos := 0; pos < r.cells; pos++ {
+		switch r.At(pos) {
+		case Blue:
+			position.WriteByte(blueChar)
+		case Red:
+			position.WriteByte(redChar)
+		default:
+			position.WriteByte(emptyChar)
+		}
+	}
+
+	position.WriteByte(' ')
+	if r.turn == Blue {
+		position.WriteByte(blueChar)
+	} else {
+		position.WriteByte(redChar)
+	}
+
+	return position.String()
+}
+
+// Get the color of a position string character
+func parseChar(c byte) (int, error) {
+	switch c {
+	case blueChar, 'x', '*', 'B', 'b':
+		return Blue, nil
+	case redChar, 'o', 'W', 'w':
+		return Red, nil
+	case emptyChar, '.', '_':
+		return Empty, nil
+	}
+	return Empty, fmt.Errorf("invalid position character %q", c)
+}
+
+// Get the game described by a position string, as returned by Position. The size of the board is
+// found from the length of the string.
+func ParsePosition(position string) (*Reversi, error) {
+	position = strings.Join(strings.Fields(position), "")
+
+	size := int(math.Sqrt(float64(len(position) - 1)))
+	g, err := GeometryOf(size)
+	if err != nil || len(position) != g.cells+1 {
+		return nil, fmt.Errorf("invalid position %q: expected a character for each position of the board and one for the turn", position)
+	}
+
+	r := &Reversi{Geometry: g}
+	r.customStart = true
+
+	for pos := 0; pos < g.cells; pos++ {
+		color, err := parseChar(position[pos])
+		if err != nil {
+			return nil, err
+		}
+		if color == Blue {
+			r.blue = r.blue.Or(bit(pos))
+		} else if color == Red {
+			r.red = r.red.Or(bit(pos))
+		}
+	}
+
+	turn, err := parseChar(position[g.cells])
+	if err != nil || turn == Empty {
+		return nil, fmt.Errorf("invalid position turn %q, expected %c or %c", position[g.cells], blueChar, redChar)
+	}
+	r.turn = turn
+	r.rehash()
+
+	return r, nil
+}
+
+// Get a string the game can be restored from with Parse: the transcript if the game started from the
+// four starting chips and a position has been played, otherwise the position string
+func (r *Reversi) Export() string {
+	if r.FromStart() && len(r.History()) > 0 {
+		return r.Transcript()
+	}
+	return r.Position()
+}
+
+// Get the game described by either a position string or a transcript. Transcripts are played on a board
+// of the given size, position strings have their own size.
+func Parse(game string, size int) (*Reversi, error) {
+	// Only transcripts have row numbers
+	if !strings.ContainsAny(game, "0123456789") && strings.TrimSpace(game) != "" {
+		return ParsePosition(game)
+	}
+	return ParseTranscript(game, size)
+}
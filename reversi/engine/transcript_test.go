@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Play random moves from the start for the given color, up to the given number of positions or the end of
+// the game, passing the turn of a color without valid positions as the players do
+func randomGame(rng *rand.Rand, size, first, positions int) *Reversi {
+	r, _ := NewSize(size, first)
+	for len(r.History()) < positions && !r.IsOver() {
+		if moves := r.ValidPositions(); len(moves) > 0 {
+			r.Play(moves[rng.Intn(len(moves))])
+		} else {
+			r.Pass()
+		}
+	}
+	if r.Moves().IsEmpty() && !r.IsOver() {
+		r.Pass()
+	}
+	return r
+}
+
+// Count the passes of a game
+func passes(r *Reversi) int {
+	count := 0
+	for _, move := range r.PlayedMoves() {
+		if move.Pos == NoMove {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	passed := 0
+	for size := MinSize; size <= MaxSize; size += 2 {
+		for game := 0; game < 50; game++ {
+			first := []int{Blue, Red}[game%2]
+			// An empty transcript can't tell which color moves first, so every game plays a position
+			r := randomGame(rng, size, first, 1+rng.Intn(size*size))
+
+			transcript := r.Transcript()
+			parsed, err := ParseTranscript(transcript, size)
+			if err != nil {
+				t.Fatalf("%vx%v game %v: parsing %q: %v", size, size, game, transcript, err)
+			}
+			if parsed.Position() != r.Position() || parsed.Hash() != r.Hash() {
+				t.Fatalf("%vx%v game %v: %q parses to %v, want %v", size, size, game, transcript, parsed.Position(), r.Position())
+			}
+			if got := parsed.Transcript(); got != transcript {
+				t.Fatalf("%vx%v game %v: transcript of the parsed game = %q, want %q", size, size, game, got, transcript)
+			}
+			if got, want := len(parsed.PlayedMoves()), len(r.PlayedMoves()); got != want || passes(parsed) != passes(r) {
+				t.Fatalf("%vx%v game %v: the parsed game has %v moves with %v passes, want %v with %v", size, size, game, got, passes(parsed), want, passes(r))
+			}
+			// The color that moved first is found from the first position
+			if moves := parsed.PlayedMoves(); len(moves) > 0 && moves[0].Color != first {
+				t.Fatalf("%vx%v game %v: %q parses with %v moving first, want %v", size, size, game, transcript, moves[0].Color, first)
+			}
+			passed += passes(r)
+		}
+	}
+	if passed == 0 {
+		t.Error("no game passed a turn")
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	tests := []struct {
+		transcript string
+		size       int
+		// Position string of the game, or "" if the transcript is rejected
+		position string
+	}{
+		{"", 8, "---------------------------OX------XO--------------------------- X"},
+		{"f5", 8, "---------------------------OX------XXX-------------------------- O"},
+		{" F5 d6\n", 8, "---------------------------OX------OXX-----O-------------------- X"},
+		// Red's first moves are different from blue's, so red moved first
+		{"c5", 8, "---------------------------OX-----OOO--------------------------- X"},
+		{"c4", 4, "-----OX--XX---X- O"},
+		{"j10", 10, ""},
+		{"f5f5", 8, ""},
+		{"f5a1", 8, ""},
+		{"f5z9", 8, ""},
+		{"f9", 8, ""},
+		{"f0", 8, ""},
+		{"f5", 6, ""},
+	}
+
+	for _, test := range tests {
+		r, err := ParseTranscript(test.transcript, test.size)
+		if test.position == "" {
+			if err == nil {
+				t.Errorf("ParseTranscript(%q, %v) = %v, want an error", test.transcript, test.size, r.Position())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTranscript(%q, %v): %v", test.transcript, test.size, err)
+		} else if position := r.Position(); position != test.position {
+			t.Errorf("ParseTranscript(%q, %v) = %v, want %v", test.transcript, test.size, position, test.position)
+		}
+	}
+}
+
+func TestPositionRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for size := MinSize; size <= MaxSize; size += 2 {
+		for game := 0; game < 20; game++ {
+			r := randomGame(rng, size, Blue, rng.Intn(size*size))
+			position := r.Position()
+			parsed, err := ParsePosition(position)
+			if err != nil {
+				t.Fatalf("%vx%v: parsing %v: %v", size, size, position, err)
+			}
+			if parsed.Size() != size || parsed.Position() != position || parsed.Turn() != r.Turn() || parsed.Hash() != r.Hash() {
+				t.Fatalf("%vx%v: %v parses to %v", size, size, position, parsed.Position())
+			}
+			if parsed.FromStart() || len(parsed.PlayedMoves()) != 0 {
+				t.Fatalf("%vx%v: the game of %v has a history", size, size, position)
+			}
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		position string
+		// Position string of the game, or "" if the position is rejected
+		want string
+	}{
+		{"----OX--XXX----- O", "----OX--XXX----- O"},
+		// Other characters for the colors, and whitespace between rows
+		{"..._\nwB..\n.bxo\n.... *", "----OX---XXO---- X"},
+		{"----OX--XXX-----O", "----OX--XXX----- O"},
+		{"----OX--XXX----", ""},
+		{"----OX--XXX----- -", ""},
+		{"----OX--XXX----- Z", ""},
+		{"----OX--XXZ----- O", ""},
+		{"--------- O", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		r, err := ParsePosition(test.position)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("ParsePosition(%q) = %v, want an error", test.position, r.Position())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePosition(%q): %v", test.position, err)
+		} else if position := r.Position(); position != test.want {
+			t.Errorf("ParsePosition(%q) = %v, want %v", test.position, position, test.want)
+		}
+	}
+}
+
+func TestExportAndParse(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// A game from the start exports its transcript, once a position has been played
+	start, _ := NewSize(6, Red)
+	if export := start.Export(); export != start.Position() {
+		t.Errorf("export of a game without moves = %q, want its position %q", export, start.Position())
+	}
+	r := randomGame(rng, 6, Red, 10)
+	if export := r.Export(); export != r.Transcript() {
+		t.Errorf("export of a game from the start = %q, want its transcript %q", export, r.Transcript())
+	}
+
+	// A game from a position string exports its position, even after moves are played
+	custom, _ := ParsePosition(r.Position())
+	custom.Play(custom.ValidPositions()[0])
+	if export := custom.Export(); strings.ContainsAny(export, "0123456789") || export != custom.Position() {
+		t.Errorf("export of a game from a position = %q, want its position %q", export, custom.Position())
+	}
+
+	// Parse reads either kind back
+	for _, game := range []*Reversi{start, r, custom} {
+		parsed, err := Parse(game.Export(), game.Size())
+		if err != nil {
+			t.Errorf("parsing %q: %v", game.Export(), err)
+		} else if parsed.Position() != game.Position() {
+			t.Errorf("%q parses to %v, want %v", game.Export(), parsed.Position(), game.Position())
+		}
+	}
+}
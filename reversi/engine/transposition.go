@@ -0,0 +1,127 @@
+package engine
+
+// Number of entries of a transposition table when no size is given, which take about 5 MB
+const DefaultTableEntries int = 1 << 16
+
+// Kinds of score of a table entry: the exact score of the position, or only a bound of it when the search
+// of the position was cut off
+const (
+	ExactScore = iota
+	LowerBound
+	UpperBound
+)
+
+// What a search found out about a position
+type TableEntry struct {
+	// Number of moves searched below the position, which tells how far the score can be trusted
+	Depth int
+	Score int
+	// ExactScore, LowerBound or UpperBound
+	Bound int
+	// Best position found, which is worth searching first when the position is seen again, or NoMove
+	Best int
+}
+
+// An entry and the position it belongs to
+type tableSlot struct {
+	hash uint64
+	// The chips of the color whose turn it is and of its opponent, to tell positions with the same hash apart
+	own, opp Bitboard
+	entry    TableEntry
+	// Search the entry was stored by, 0 for an empty slot
+	generation uint32
+}
+
+// A bounded table of search results, indexed by the Zobrist hash of the positions, so that a search
+// recognizes the positions it reaches by different orders of moves, and a later search can use what an
+// earlier one found. Every entry keeps the chips of its position, so two positions with the same hash
+// never share an entry.
+//
+// Entries are kept in buckets of two. The first keeps the most valuable entry: the deepest one of the
+// current search, which a shallower one doesn't replace. The second keeps the most recent entry, which
+// anything replaces. A table is not safe for concurrent use.
+type TranspositionTable struct {
+	buckets    [][2]tableSlot
+	mask       uint64
+	generation uint32
+	// Number of probes, of probes that found their position, of probes that found another position with the
+	// same hash, and of entries stored
+	Probes, Hits, Collisions, Stores int
+}
+
+// Initialize and return a table with room for about the given number of entries, rounded down to a
+// power of two. The table has at least one bucket.
+func NewTranspositionTable(entries int) *TranspositionTable {
+	buckets := 1
+	for buckets*4 <= entries {
+		buckets *= 2
+	}
+	return &TranspositionTable{buckets: make([][2]tableSlot, buckets), mask: uint64(buckets - 1), generation: 1}
+}
+
+// Get the number of entries the table has room for
+func (t *TranspositionTable) Len() int {
+	return 2 * len(t.buckets)
+}
+
+// Start a new search: the entries of earlier searches are kept, but are the first to be replaced
+func (t *TranspositionTable) NewSearch() {
+	t.generation++
+}
+
+// Remove every entry
+func (t *TranspositionTable) Clear() {
+	for i := range t.buckets {
+		t.buckets[i] = [2]tableSlot{}
+	}
+	t.generation = 1
+}
+
+// Get the entry of the position with the given hash and chips, and whether there is one
+func (t *TranspositionTable) Probe(hash uint64, own, opp Bitboard) (TableEntry, bool) {
+	t.Probes++
+	bucket := &t.buckets[hash&t.mask]
+	for i := range bucket {
+		slot := &bucket[i]
+		if slot.generation == 0 || slot.hash != hash {
+			continue
+		}
+		if slot.own != own || slot.opp != opp {
+			t.Collisions++
+			continue
+		}
+		t.Hits++
+		return slot.entry, true
+	}
+	return TableEntry{}, false
+}
+
+// Store the entry of the position with the given hash and chips, replacing the one it had
+func (t *TranspositionTable) Store(hash uint64, own, opp Bitboard, entry TableEntry) {
+	t.Stores++
+	bucket := &t.buckets[hash&t.mask]
+	slot := tableSlot{hash: hash, own: own, opp: opp, entry: entry, generation: t.generation}
+
+	first := &bucket[0]
+	if first.generation != 0 && first.hash == hash && first.own == own && first.opp == opp {
+		// A shallower result of the same search doesn't replace a deeper one, but can be kept as the most
+		// recent entry
+		if first.generation == t.generation && entry.Depth < first.entry.Depth {
+			bucket[1] = slot
+			return
+		}
+		*first = slot
+		return
+	}
+
+	// The deepest entry of the current search stays in the first slot, and the one it replaces is still
+	// the most recent
+	if first.generation != t.generation || entry.Depth >= first.entry.Depth {
+		if first.generation != 0 {
+			bucket[1] = *first
+		}
+		*first = slot
+		return
+	}
+	bucket[1] = slot
+}
@@ -0,0 +1,86 @@
+package engine
+
+import "testing"
+
+// Positions of the table test, all in the one bucket of a table with room for 2 entries. b2 has the hash
+// of b but other chips.
+var tablePositions = map[string]struct {
+	hash     uint64
+	own, opp Bitboard
+}{
+	"a":  {1, BitboardOf(0), BitboardOf(1)},
+	"b":  {2, BitboardOf(2), BitboardOf(3)},
+	"b2": {2, BitboardOf(3), BitboardOf(2)},
+	"c":  {3, BitboardOf(4), BitboardOf(5)},
+	"d":  {4, BitboardOf(6), BitboardOf(7)},
+}
+
+func TestTranspositionTable(t *testing.T) {
+	table := NewTranspositionTable(2)
+	if table.Len() != 2 {
+		t.Fatalf("table has room for %v entries, want 2", table.Len())
+	}
+
+	// Each step stores an entry of the given depth, starts a new search, or probes the positions, which
+	// must find the given depths, and nothing for the positions not given
+	steps := []struct {
+		name   string
+		store  string
+		depth  int
+		search bool
+		found  map[string]int
+	}{
+		{name: "an empty table finds nothing", found: map[string]int{}},
+		{name: "a first entry", store: "a", depth: 5, found: map[string]int{"a": 5}},
+		{name: "a shallower entry goes to the second slot", store: "b", depth: 3, found: map[string]int{"a": 5, "b": 3}},
+		{name: "the second slot takes the most recent entry", store: "c", depth: 2, found: map[string]int{"a": 5, "c": 2}},
+		{name: "a deeper entry takes the first slot and moves the first entry to the second", store: "d", depth: 7, found: map[string]int{"a": 5, "d": 7}},
+		{name: "a position with the same hash but other chips is not found", store: "b", depth: 1, found: map[string]int{"d": 7, "b": 1}},
+		{name: "a shallower result of the same position keeps the deeper one first", store: "d", depth: 4, found: map[string]int{"d": 7}},
+		{name: "a deeper result of the same position replaces it", store: "d", depth: 9, found: map[string]int{"d": 9}},
+		{name: "a new search", search: true, found: map[string]int{"d": 9}},
+		{name: "entries of earlier searches are replaced first, however deep", store: "c", depth: 1, found: map[string]int{"c": 1, "d": 9}},
+		{name: "a shallower entry replaces the entry of the earlier search in the second slot", store: "a", depth: 0, found: map[string]int{"c": 1, "a": 0}},
+	}
+
+	for _, step := range steps {
+		if step.search {
+			table.NewSearch()
+		}
+		if step.store != "" {
+			p := tablePositions[step.store]
+			table.Store(p.hash, p.own, p.opp, TableEntry{Depth: step.depth, Score: step.depth, Bound: ExactScore, Best: NoMove})
+		}
+
+		for name, p := range tablePositions {
+			entry, ok := table.Probe(p.hash, p.own, p.opp)
+			want, wantOk := step.found[name]
+			if ok != wantOk || (ok && entry.Depth != want) {
+				t.Errorf("%v: probing %v gives %+v, %v, want depth %v, %v", step.name, name, entry, ok, want, wantOk)
+			}
+		}
+	}
+}
+
+func TestTranspositionTableVerifiesChips(t *testing.T) {
+	table := NewTranspositionTable(DefaultTableEntries)
+	b, b2 := tablePositions["b"], tablePositions["b2"]
+	table.Store(b.hash, b.own, b.opp, TableEntry{Depth: 3, Score: 10, Bound: LowerBound, Best: 5})
+
+	if _, ok := table.Probe(b2.hash, b2.own, b2.opp); ok {
+		t.Errorf("probing chips with the hash of other chips finds their entry")
+	}
+	if table.Collisions != 1 || table.Hits != 0 {
+		t.Errorf("%v collisions and %v hits, want 1 and 0", table.Collisions, table.Hits)
+	}
+
+	entry, ok := table.Probe(b.hash, b.own, b.opp)
+	if want := (TableEntry{Depth: 3, Score: 10, Bound: LowerBound, Best: 5}); !ok || entry != want {
+		t.Errorf("probing the stored position gives %+v, %v, want %+v", entry, ok, want)
+	}
+
+	table.Clear()
+	if _, ok := table.Probe(b.hash, b.own, b.opp); ok {
+		t.Errorf("probing a cleared table finds an entry")
+	}
+}
@@ -0,0 +1,78 @@
+package engine
+
+// Random keys of the Zobrist hashing of games: one for each color and position, and one for the turn of
+// red. The hash of a game is the XOR of the keys of its chips, and of the turn key if red is to move, so a
+// move only changes the keys of the chip it places, of the chips it flips and of the turn.
+var zobristChips [2][MaxSize * MaxSize]uint64
+
+// XOR of the keys of both colors at each position, which is what flipping the chip there changes
+var zobristFlips [MaxSize * MaxSize]uint64
+
+var zobristTurn uint64
+
+// Generate the keys from a fixed seed, so that hashes are the same in every run
+func init() {
+	state := uint64(0x5eed)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		return splitMix64(state)
+	}
+
+	for pos := range zobristFlips {
+		zobristChips[0][pos] = next()
+		zobristChips[1][pos] = next()
+		zobristFlips[pos] = zobristChips[0][pos] ^ zobristChips[1][pos]
+	}
+	zobristTurn = next()
+}
+
+// Mix the bits of x with the finalizer of SplitMix64
+func splitMix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Get the keys of the chips of the given color
+func chipKeys(color int) *[MaxSize * MaxSize]uint64 {
+	if color == Blue {
+		return &zobristChips[0]
+	}
+	return &zobristChips[1]
+}
+
+// Get the key of the given turn
+func turnKey(turn int) uint64 {
+	if turn == Red {
+		return zobristTurn
+	}
+	return 0
+}
+
+// Get the hash of the given chips and turn
+func hashOf(blue, red Bitboard, turn int) uint64 {
+	hash := turnKey(turn)
+	for ; !blue.IsEmpty(); blue = blue.withoutFirst() {
+		hash ^= zobristChips[0][blue.First()]
+	}
+	for ; !red.IsEmpty(); red = red.withoutFirst() {
+		hash ^= zobristChips[1][red.First()]
+	}
+	return hash
+}
+
+// Get what the given color placing a chip at pos and flipping the given chips changes in the hash. The
+// turn is not included.
+func moveHash(color, pos int, flipped Bitboard) uint64 {
+	hash := chipKeys(color)[pos]
+	for ; !flipped.IsEmpty(); flipped = flipped.withoutFirst() {
+		hash ^= zobristFlips[flipped.First()]
+	}
+	return hash
+}
+
+// Get the Zobrist hash of the chips and the turn. Games with the same chips and turn have the same hash,
+// however they were reached, so searches can recognize the positions they have already seen.
+func (r *Reversi) Hash() uint64 {
+	return r.hash
+}
@@ -0,0 +1,3 @@
+module github.com/M-Balghonaim/Reversi-AI/reversi
+
+go 1.16
@@ -1,12 +1,142 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/console"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/protocol"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/server"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
 func main() {
 
+	// Read the game settings. Settings that are not given are asked for if there is a person at the terminal.
+	var options Options
+	flag.DurationVar(&options.TimeControl.MoveTime, "movetime", engine.DefaultMoveTime, "maximum time the computer may take per move")
+	flag.DurationVar(&options.TimeControl.Clock, "clock", 0, "total time each side has for the whole game (0 for no game clock)")
+	flag.DurationVar(&options.TimeControl.Increment, "increment", 0, "time added to a side's clock after each of its moves")
+	flag.IntVar(&options.Size, "size", engine.DefaultSize, fmt.Sprintf("number of positions in a row or column of the board: an even number from %v to %v", engine.MinSize, engine.MaxSize))
+	flag.IntVar(&options.EndgameEmpties, "endgame", engine.DefaultEndgameEmpties, "number of empty positions at which the computer solves the rest of the game exactly")
+	players := strings.Join(console.PlayerNames(), ", ")
+	flag.StringVar(&options.Blue, "blue", "", "player of the blue chips: "+players)
+	flag.StringVar(&options.Red, "red", "", "player of the red chips: "+players)
+	flag.StringVar(&options.Color, "color", "", "color of the person playing against the computer when -blue and -red are not given: red or blue")
+	flag.StringVar(&options.First, "first", "", "color that moves first: red or blue")
+	flag.StringVar(&options.Start, "start", "", "transcript (such as f5d6c3) or position string to start the game from")
+	flag.IntVar(&options.Playouts, "playouts", engine.Playouts, "number of playouts for each valid position")
+	flag.StringVar(&options.Book, "book", "builtin", "opening book of the computer: builtin, none, or book files, comma-separated to combine them")
+	flag.Int64Var(&options.Seed, "seed", 0, "seed for the random numbers, the same seed and settings play the same games (0 picks one at random)")
+	protocolName := flag.String("protocol", "", "talk to another program over the standard input and output instead of playing on the terminal: nboard or gtp")
+	engineSpec := flag.String("engine", "mcts", "player of the computer in the -protocol and -http modes: "+strings.Join(engine.PlayerNames(), ", "))
+	httpAddr := flag.String("http", "", "serve games over HTTP on the given address, such as :8080, instead of playing on the terminal")
+	var serverSettings server.Settings
+	flag.DurationVar(&serverSettings.Expiry, "expiry", server.DefaultExpiry, "time without requests after which a game served over HTTP is removed")
+	flag.IntVar(&serverSettings.MaxGames, "maxgames", 100, "maximum number of games served over HTTP at the same time (0 for no limit)")
+	origins := flag.String("origins", "", "comma-separated origins of other sites whose pages may open WebSockets to the games served over HTTP, such as https://example.com, or * for any")
+	flag.Parse()
+	if *origins != "" {
+		serverSettings.AllowedOrigins = strings.Split(*origins, ",")
+	}
+
+	if *httpAddr != "" {
+		log.Fatal(runServer(options, *httpAddr, *engineSpec, serverSettings))
+	}
+
+	if *protocolName != "" {
+		if err := runProtocol(options, *protocolName, *engineSpec); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize a new game
-	game := NewGame()
+	game, err := NewGame(options)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Play turns
 	for !game.End {
-		game.PlayTurn()
+		if err := game.PlayTurn(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// Get the settings of the computer players of the -protocol and -http modes
+func playerConfig(options Options) (engine.PlayerConfig, error) {
+	book, err := console.LoadBook(options.Book, options.Size)
+	if err != nil {
+		return engine.PlayerConfig{}, err
+	}
+	return engine.PlayerConfig{EndgameEmpties: options.EndgameEmpties, Playouts: options.Playouts, Seed: options.Seed, Book: book}, nil
+}
+
+// Get the color that moves first in the -protocol and -http modes: black (blue) as in standard Othello,
+// unless -first says otherwise
+func firstColor(options Options) (int, error) {
+	if options.First == "" {
+		return engine.Blue, nil
 	}
+	return console.ParseColor(options.First)
+}
+
+// Play the moves of the given player spec over the given protocol, on the standard input and output
+func runProtocol(options Options, name, spec string) error {
+	config, err := playerConfig(options)
+	if err != nil {
+		return err
+	}
+	player, err := engine.NewPlayer(spec, config)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "nboard":
+		n, err := protocol.NewNBoard(player, options.TimeControl, options.Size)
+		if err != nil {
+			return err
+		}
+		return n.Run(os.Stdin, os.Stdout)
+	case "gtp":
+		first, err := firstColor(options)
+		if err != nil {
+			return err
+		}
+		g, err := protocol.NewGTP(player, options.TimeControl, options.Size, first)
+		if err != nil {
+			return err
+		}
+		return g.Run(os.Stdin, os.Stdout)
+	}
+	return fmt.Errorf("unknown protocol %q, expected nboard or gtp", name)
+}
+
+// Serve games played against the given player spec over HTTP on the given address, until the server fails
+func runServer(options Options, addr, spec string, settings server.Settings) error {
+	var err error
+	if settings.Config, err = playerConfig(options); err != nil {
+		return err
+	}
+	if settings.First, err = firstColor(options); err != nil {
+		return err
+	}
+	settings.Size = options.Size
+	settings.Engine = spec
+	settings.MoveTime = options.TimeControl.MoveTime
+
+	s, err := server.NewServer(settings)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	log.Printf("Serving games on %v, open http://%v/ in a browser to play", addr, addr)
+	return http.ListenAndServe(addr, s)
 }
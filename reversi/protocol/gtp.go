@@ -0,0 +1,357 @@
+package protocol
+
+import (
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An engine speaking a text protocol modeled on the Go Text Protocol (GTP), for driving the engine from
+// scripts. Every command is a line, optionally starting with a number as its id, and every answer is
+// "=<id> <result>" on success or "?<id> <error>" on failure, followed by a blank line. Black is blue and
+// white is red, and moves are coordinates such as "f5" or "pass".
+type GTP struct {
+	game   *engine.Reversi
+	player engine.Player
+	clock  *engine.Clock
+	// Color that moves first in new games
+	first int
+	// Number of moves of the game played by each play and genmove, counting the pass of the other color
+	// played before the move when it can't move, so that undo takes them back together
+	plays []int
+}
+
+// A GTP command: it gets the arguments and returns the result, or an error answered as a failure
+type gtpCommand func(g *GTP, l *lineReader, args []string) (string, error)
+
+// Commands of the protocol
+var gtpCommands map[string]gtpCommand
+
+func init() {
+	gtpCommands = map[string]gtpCommand{
+		"protocol_version": func(g *GTP, l *lineReader, args []string) (string, error) { return "2", nil },
+		"name":             func(g *GTP, l *lineReader, args []string) (string, error) { return EngineName, nil },
+		"version":          func(g *GTP, l *lineReader, args []string) (string, error) { return "1.0", nil },
+		"known_command":    (*GTP).knownCommand,
+		"list_commands":    (*GTP).listCommands,
+		"quit":             (*GTP).quit,
+		"boardsize":        (*GTP).boardSize,
+		"clear_board":      (*GTP).clearBoard,
+		"play":             (*GTP).play,
+		"genmove":          (*GTP).genMove,
+		"undo":             (*GTP).undo,
+		"showboard":        (*GTP).showBoard,
+		"final_score":      (*GTP).finalScore,
+		"time_settings":    (*GTP).timeSettings,
+		"time_left":        (*GTP).timeLeft,
+	}
+}
+
+// Initialize and return an engine playing the moves of the given player within the given time, starting
+// with a new game on a board of the given size where the given color moves first
+func NewGTP(player engine.Player, timeControl engine.TimeControl, size, first int) (*GTP, error) {
+	game, err := engine.NewSize(size, first)
+	if err != nil {
+		return nil, err
+	}
+	return &GTP{game: game, player: player, clock: engine.NewClock(timeControl), first: first}, nil
+}
+
+// Answer the commands of the given input on the given output until the input ends or quit is sent
+func (g *GTP) Run(in io.Reader, out io.Writer) error {
+	l := newLineReader(in, out)
+	return l.run(func(line string) error {
+		g.handle(l, line)
+		return nil
+	})
+}
+
+// Answer one command line
+func (g *GTP) handle(l *lineReader, line string) {
+	// Everything after a # is a comment
+	if comment := strings.IndexByte(line, '#'); comment >= 0 {
+		line = line[:comment]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	id := ""
+	if _, err := strconv.Atoi(fields[0]); err == nil {
+		id, fields = fields[0], fields[1:]
+		if len(fields) == 0 {
+			l.printf("?%v missing command\n", id)
+			return
+		}
+	}
+
+	command, ok := gtpCommands[strings.ToLower(fields[0])]
+	if !ok {
+		l.printf("?%v unknown command\n", id)
+		return
+	}
+
+	result, err := command(g, l, fields[1:])
+	if err != nil {
+		l.printf("?%v %v\n", id, err)
+		return
+	}
+	l.printf("=%v %v\n", id, result)
+}
+
+// Answer whether the command is known: "true" or "false"
+func (g *GTP) knownCommand(l *lineReader, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected known_command <command>")
+	}
+	_, ok := gtpCommands[strings.ToLower(args[0])]
+	return strconv.FormatBool(ok), nil
+}
+
+// Answer the names of the commands, one per line
+func (g *GTP) listCommands(l *lineReader, args []string) (string, error) {
+	var names []string
+	for name := range gtpCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\n"), nil
+}
+
+// Stop answering commands, once this one is answered
+func (g *GTP) quit(l *lineReader, args []string) (string, error) {
+	l.quit = true
+	return "", nil
+}
+
+// Start a new game on a board of the given size
+func (g *GTP) boardSize(l *lineReader, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected boardsize <size>")
+	}
+	size, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid size %q", args[0])
+	}
+	game, err := engine.NewSize(size, g.first)
+	if err != nil {
+		return "", fmt.Errorf("unacceptable size: %v", err)
+	}
+	g.game = game
+	g.plays = nil
+	return "", nil
+}
+
+// Start a new game on the same board, with a full clock
+func (g *GTP) clearBoard(l *lineReader, args []string) (string, error) {
+	g.game, _ = engine.NewSize(g.game.Size(), g.first)
+	g.plays = nil
+	g.clock = engine.NewClock(g.clock.Control)
+	return "", nil
+}
+
+// Get the color named by a GTP color: black, white, b or w, or blue and red
+func parseGTPColor(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "b", "black", "blue":
+		return engine.Blue, nil
+	case "w", "white", "red":
+		return engine.Red, nil
+	}
+	return engine.Empty, fmt.Errorf("invalid color %q, expected black or white", name)
+}
+
+// Get the position of a GTP move, a coordinate such as "f5" or "pass"
+func parseGTPMove(r *engine.Reversi, move string) (int, error) {
+	if strings.EqualFold(move, "pass") {
+		return engine.NoMove, nil
+	}
+	return r.ParsePos(move)
+}
+
+// Get the GTP name of a position: its coordinate, or "pass" for NoMove
+func gtpMove(r *engine.Reversi, pos int) string {
+	if pos == engine.NoMove {
+		return "pass"
+	}
+	return r.PosName(pos)
+}
+
+// Play a move of the given color: play <color> <move>
+func (g *GTP) play(l *lineReader, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("expected play <color> <move>")
+	}
+	color, err := parseGTPColor(args[0])
+	if err != nil {
+		return "", err
+	}
+	pos, err := parseGTPMove(g.game, args[1])
+	if err != nil {
+		return "", err
+	}
+	played := len(g.game.PlayedMoves())
+	if err := playMove(g.game, color, pos); err != nil {
+		return "", fmt.Errorf("illegal move: %v", err)
+	}
+	g.plays = append(g.plays, len(g.game.PlayedMoves())-played)
+	return "", nil
+}
+
+// Play the computer's move for the given color and answer it: genmove <color>
+func (g *GTP) genMove(l *lineReader, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected genmove <color>")
+	}
+	color, err := parseGTPColor(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	if g.game.IsOver() {
+		return gtpMove(g.game, engine.NoMove), nil
+	}
+
+	// The other color passes first if it can't move
+	played := len(g.game.PlayedMoves())
+	if color != g.game.Turn() && g.game.Moves().IsEmpty() {
+		g.game.Pass()
+	}
+	if color != g.game.Turn() {
+		return "", fmt.Errorf("it's not the turn of %v", colorName(color))
+	}
+
+	startTime := time.Now()
+	pos, _ := g.player.BestMove(g.game, g.clock.Budget(g.game))
+	g.clock.Spend(color, time.Since(startTime))
+
+	if err := playMove(g.game, color, pos); err != nil {
+		return "", err
+	}
+	g.plays = append(g.plays, len(g.game.PlayedMoves())-played)
+	return gtpMove(g.game, pos), nil
+}
+
+// Take back the last move played with play or genmove, along with the pass played before it for the other
+// color
+func (g *GTP) undo(l *lineReader, args []string) (string, error) {
+	if len(g.plays) == 0 {
+		return "", fmt.Errorf("cannot undo")
+	}
+	for i := 0; i < g.plays[len(g.plays)-1]; i++ {
+		g.game.Undo()
+	}
+	g.plays = g.plays[:len(g.plays)-1]
+	return "", nil
+}
+
+// Answer the board as rows of X for black, O for white and - for empty positions, under the column letters
+// and after the row numbers, then the turn and the scores, such as "black to move, black 2, white 2"
+func (g *GTP) showBoard(l *lineReader, args []string) (string, error) {
+	r := g.game
+	var board strings.Builder
+
+	board.WriteString("\n   ")
+	for col := 0; col < r.Size(); col++ {
+		fmt.Fprintf(&board, " %c", 'a'+col)
+	}
+	for pos, chip := range r.Board() {
+		if pos%r.Size() == 0 {
+			fmt.Fprintf(&board, "\n%3d", pos/r.Size()+1)
+		}
+		switch chip {
+		case engine.Blue:
+			board.WriteString(" X")
+		case engine.Red:
+			board.WriteString(" O")
+		default:
+			board.WriteString(" -")
+		}
+	}
+
+	status := colorName(r.Turn()) + " to move"
+	if r.IsOver() {
+		status = "game over"
+	}
+	fmt.Fprintf(&board, "\n%v, black %v, white %v", status, r.Score(engine.Blue), r.Score(engine.Red))
+	return board.String(), nil
+}
+
+// Answer the score of the chips on the board, as the color ahead and its lead, such as "B+10" or "W+4",
+// or "0" for a tie. The game doesn't have to be over.
+func (g *GTP) finalScore(l *lineReader, args []string) (string, error) {
+	lead := g.game.Score(engine.Blue) - g.game.Score(engine.Red)
+	if lead > 0 {
+		return fmt.Sprintf("B+%v", lead), nil
+	} else if lead < 0 {
+		return fmt.Sprintf("W+%v", -lead), nil
+	}
+	return "0", nil
+}
+
+// Parse the integer arguments of a command
+func parseGTPInts(args []string, usage string) ([]int, error) {
+	numbers := make([]int, len(args))
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number %q, expected %v", arg, usage)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
+
+// Set the time of both colors: time_settings <main time> <byo-yomi time> <byo-yomi stones>, in seconds.
+// The main time is the game clock, and a byo-yomi period adds its time shared by its moves to the clock
+// after every move. With no main time, byo-yomi is the time per move, and with no time at all there is
+// no limit but the default time per move.
+func (g *GTP) timeSettings(l *lineReader, args []string) (string, error) {
+	usage := "time_settings <main time> <byo-yomi time> <byo-yomi stones>"
+	if len(args) != 3 {
+		return "", fmt.Errorf("expected %v", usage)
+	}
+	numbers, err := parseGTPInts(args, usage)
+	if err != nil {
+		return "", err
+	}
+
+	mainTime := time.Duration(numbers[0]) * time.Second
+	var perMove time.Duration
+	if numbers[2] > 0 {
+		perMove = time.Duration(numbers[1]) * time.Second / time.Duration(numbers[2])
+	}
+
+	control := engine.TimeControl{Clock: mainTime, Increment: perMove}
+	if mainTime == 0 {
+		control = engine.TimeControl{MoveTime: perMove}
+	}
+	g.clock = engine.NewClock(control)
+	return "", nil
+}
+
+// Set the time a color has left on its clock: time_left <color> <time> <stones>, in seconds
+func (g *GTP) timeLeft(l *lineReader, args []string) (string, error) {
+	usage := "time_left <color> <time> <stones>"
+	if len(args) != 3 {
+		return "", fmt.Errorf("expected %v", usage)
+	}
+	color, err := parseGTPColor(args[0])
+	if err != nil {
+		return "", err
+	}
+	numbers, err := parseGTPInts(args[1:], usage)
+	if err != nil {
+		return "", err
+	}
+
+	if g.clock.HasClock() {
+		g.clock.SetRemaining(color, time.Duration(numbers[0])*time.Second)
+	}
+	return "", nil
+}
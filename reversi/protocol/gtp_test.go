@@ -0,0 +1,178 @@
+package protocol
+
+import (
+	"bytes"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run a GTP engine on the given lines of commands, and get it with its answers, without their blank lines
+func runGTP(t *testing.T, size int, commands ...string) (*GTP, []string) {
+	a := newTestAlphaBeta(0)
+	a.MaxDepth = 2
+	g, err := NewGTP(a, engine.TimeControl{}, size, engine.Blue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := g.Run(strings.NewReader(strings.Join(commands, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every answer ends with a blank line
+	output := out.String()
+	if !strings.HasSuffix(output, "\n\n") {
+		t.Fatalf("output %q doesn't end with a blank line", output)
+	}
+	return g, strings.Split(strings.TrimSuffix(output, "\n\n"), "\n\n")
+}
+
+func TestGTPRun(t *testing.T) {
+	g, answers := runGTP(t, engine.DefaultSize,
+		"1 boardsize 6",
+		"2 play black e4",
+		"genmove white # the engine's move",
+		"4 undo",
+		"5 showboard",
+		"6 final_score",
+		"7 play black a1",
+		"8 play red pass",
+		"9 time_settings 60 10 5",
+		"10 boardsize 3",
+		"11 fly",
+		"",
+		"12",
+		"13 undo",
+	)
+
+	board := strings.Join([]string{
+		"",
+		"    a b c d e f",
+		"  1 - - - - - -",
+		"  2 - - - - - -",
+		"  3 - - O X - -",
+		"  4 - - X X X -",
+		"  5 - - - - - -",
+		"  6 - - - - - -",
+		"white to move, black 4, white 1",
+	}, "\n")
+	want := []string{
+		"=1 ",
+		"=2 ",
+		"",
+		"=4 ",
+		"=5 " + board,
+		"=6 B+3",
+		"?7 illegal move: ",
+		"?8 illegal move: ",
+		"=9 ",
+		"?10 unacceptable size: ",
+		"?11 unknown command",
+		"?12 missing command",
+		"=13 ",
+	}
+	if len(answers) != len(want) {
+		t.Fatalf("answers = %q, want %v answers", answers, len(want))
+	}
+	for i, answer := range answers {
+		// Failures are only checked up to their error, and genmove below
+		if strings.HasPrefix(want[i], "=") && answer != want[i] || !strings.HasPrefix(answer, want[i]) {
+			t.Errorf("answer %v = %q, want %q", i+1, answer, want[i])
+		}
+	}
+
+	// genmove answers a valid position of white, without an id
+	r, _ := engine.NewSize(6, engine.Blue)
+	e4, _ := r.ParsePos("e4")
+	r.Play(e4)
+	if !strings.HasPrefix(answers[2], "= ") {
+		t.Errorf("answer to genmove = %q, want = <move>", answers[2])
+	} else if pos, err := r.ParsePos(strings.TrimPrefix(answers[2], "= ")); err != nil || !r.IsValidPosition(pos) {
+		t.Errorf("genmove answers %q, which isn't a valid position of white", answers[2])
+	}
+
+	// The second undo takes e4 back, and the time settings are a clock of 60s with 2s per move
+	if start, _ := engine.NewSize(6, engine.Blue); g.game.Position() != start.Position() {
+		t.Errorf("the game after undoing every move is %v, want the start", g.game.Position())
+	}
+	if control := (engine.TimeControl{Clock: time.Minute, Increment: 2 * time.Second}); g.clock.Control != control {
+		t.Errorf("time settings = %+v, want %+v", g.clock.Control, control)
+	}
+}
+
+func TestGTPUndoOfPlayAfterPass(t *testing.T) {
+	// A game where a color has to pass, with the position before the pass and the move after it
+	rng := rand.New(rand.NewSource(1))
+	var r *engine.Reversi
+	for r == nil {
+		game := randomGTPGame(rng)
+		moves := game.PlayedMoves()
+		for i := 1; i+1 < len(moves); i++ {
+			if moves[i].Pos == engine.NoMove {
+				r = game
+				for len(game.PlayedMoves()) > i+2 {
+					game.Undo()
+				}
+				break
+			}
+		}
+	}
+
+	// The pass is left out of the commands
+	commands := append(playCommands(r), "undo", "undo")
+	g, answers := runGTP(t, 6, commands...)
+	for i, answer := range answers {
+		if answer != "= " {
+			t.Fatalf("answer to %q = %q", commands[i], answer)
+		}
+	}
+
+	// The first undo takes back the move and the pass before it, the second the move before the pass
+	want := r.Copy()
+	for i := 0; i < 3; i++ {
+		want.Undo()
+	}
+	if g.game.Position() != want.Position() || len(g.game.PlayedMoves()) != len(want.PlayedMoves()) {
+		t.Errorf("the game after undoing the move after a pass is %v, want %v", g.game.Position(), want.Position())
+	}
+
+	// A move refused after the pass leaves the turn as it was
+	before := r.Copy()
+	before.Undo()
+	before.Undo()
+	g, answers = runGTP(t, 6, append(playCommands(before), "play "+colorName(-before.Turn())+" pass")...)
+	if answer := answers[len(answers)-1]; !strings.HasPrefix(answer, "? ") {
+		t.Errorf("answer to a pass of a color with valid positions = %q, want a failure", answer)
+	}
+	if g.game.Turn() != before.Turn() || len(g.game.PlayedMoves()) != len(before.PlayedMoves()) {
+		t.Errorf("a refused move changed the game to %v", g.game.Position())
+	}
+}
+
+// Get the commands playing the moves of a game on a 6 by 6 board, leaving out the passes as GTP
+// controllers do
+func playCommands(r *engine.Reversi) []string {
+	commands := []string{"boardsize 6"}
+	for _, move := range r.PlayedMoves() {
+		if move.Pos != engine.NoMove {
+			commands = append(commands, "play "+colorName(move.Color)+" "+r.PosName(move.Pos))
+		}
+	}
+	return commands
+}
+
+// Play a game of random moves from the start on a 6 by 6 board to the end
+func randomGTPGame(rng *rand.Rand) *engine.Reversi {
+	r, _ := engine.NewSize(6, engine.Blue)
+	for !r.IsOver() {
+		if moves := r.ValidPositions(); len(moves) > 0 {
+			r.Play(moves[rng.Intn(len(moves))])
+		} else {
+			r.Pass()
+		}
+	}
+	return r
+}
@@ -0,0 +1,279 @@
+package protocol
+
+import (
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Name the engine gives itself to the programs it talks to
+const EngineName = "Reversi"
+
+// An engine speaking the NBoard protocol, which Othello GUIs such as NBoard use to talk to engines. The GUI
+// sends the game with "set game", the moves played with "move", and asks for a move with "go" or for the
+// ratings of the best moves with "hint". Blue plays the black chips of the GUI and red the white chips.
+type NBoard struct {
+	game   *engine.Reversi
+	player engine.Player
+	clock  *engine.Clock
+}
+
+// Initialize and return an engine playing the moves of the given player within the given time, starting
+// with a new game on a board of the given size
+func NewNBoard(player engine.Player, timeControl engine.TimeControl, size int) (*NBoard, error) {
+	game, err := engine.NewSize(size, engine.Blue)
+	if err != nil {
+		return nil, err
+	}
+	return &NBoard{game: game, player: player, clock: engine.NewClock(timeControl)}, nil
+}
+
+// Answer the commands of the given input on the given output until the input ends. The GUI is told about
+// commands that fail with a status line.
+func (n *NBoard) Run(in io.Reader, out io.Writer) error {
+	l := newLineReader(in, out)
+	return l.run(func(line string) error {
+		if err := n.handle(l, line); err != nil {
+			l.printf("status %v", err)
+		}
+		return nil
+	})
+}
+
+// Answer one command
+func (n *NBoard) handle(l *lineReader, line string) error {
+	fields := strings.SplitN(line, " ", 2)
+	command, args := fields[0], ""
+	if len(fields) == 2 {
+		args = strings.TrimSpace(fields[1])
+	}
+
+	switch command {
+	case "nboard":
+		l.printf("set myname %v", EngineName)
+	case "set":
+		return n.set(args)
+	case "move":
+		return n.move(args)
+	case "go":
+		n.goMove(l)
+	case "hint":
+		return n.hint(l, args)
+	case "ping":
+		// Commands are answered in order, so every earlier command is done
+		l.printf("pong %v", args)
+	case "learn":
+		l.println("learned")
+	case "analyze":
+		// The engine has nothing to learn from a finished game
+	case "quit":
+		l.quit = true
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+	return nil
+}
+
+// Change a setting: "game" to the given GGF game record, or "depth" to the given number of moves, which
+// limits the search of alpha-beta players, and which the GUI is told other players ignore. Other settings,
+// such as "contempt", are ignored. A game without moves played starts with a full clock.
+func (n *NBoard) set(args string) error {
+	fields := strings.SplitN(args, " ", 2)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid setting %q, expected set <name> <value>", args)
+	}
+
+	switch fields[0] {
+	case "game":
+		game, err := parseGGF(fields[1])
+		if err != nil {
+			return err
+		}
+		n.game = game
+		if len(game.PlayedMoves()) == 0 {
+			n.clock = engine.NewClock(n.clock.Control)
+		}
+	case "depth":
+		depth, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil || depth < 1 {
+			return fmt.Errorf("invalid depth %q", fields[1])
+		}
+		a, ok := n.player.(*engine.AlphaBeta)
+		if !ok {
+			return fmt.Errorf("depth %v ignored, only alphabeta searches to a depth", depth)
+		}
+		a.MaxDepth = depth
+	}
+	return nil
+}
+
+// Play a move of the GUI, such as "F5", "F5/1.00/2.5" with its evaluation and time, or "PA" for a pass
+func (n *NBoard) move(args string) error {
+	pos, err := parseGGFMove(n.game, args)
+	if err != nil {
+		return err
+	}
+
+	// The move is played by whoever can play it
+	color := n.game.Turn()
+	if pos != engine.NoMove && n.game.Moves().IsEmpty() {
+		color *= -1
+	}
+	return playMove(n.game, color, pos)
+}
+
+// Answer the move of the engine for the current turn as "=== F5/<evaluation>/<seconds>", where the
+// evaluation is only given once the endgame is solved. The move is not played until the GUI sends it back,
+// but its time is taken from the clock of the side to move.
+func (n *NBoard) goMove(l *lineReader) {
+	l.println("status thinking")
+	startTime := time.Now()
+	pos, stats := n.player.BestMove(n.game, n.clock.Budget(n.game))
+	n.clock.Spend(n.game.Turn(), time.Since(startTime))
+
+	if stats.Nodes > 0 {
+		l.printf("nodestats %v %.3f", stats.Nodes, stats.Elapsed.Seconds())
+	}
+
+	evaluation := ""
+	if stats.Proven {
+		evaluation = strconv.Itoa(stats.Score)
+	}
+	l.printf("=== %v/%v/%.3f", ggfMove(n.game, pos), evaluation, stats.Elapsed.Seconds())
+	l.println("status")
+}
+
+// Answer the ratings of the given number of best moves, best first, each as
+// "search <move> <evaluation> 0 <depth>". Players that can't rate moves only answer their best one. The
+// time of the search is taken from the clock of the side to move.
+func (n *NBoard) hint(l *lineReader, args string) error {
+	count, err := strconv.Atoi(args)
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid number of hints %q", args)
+	}
+
+	l.println("status thinking")
+	startTime := time.Now()
+	var evaluations []engine.Evaluation
+	var stats engine.Stats
+	if analyzer, ok := n.player.(engine.Analyzer); ok {
+		evaluations, stats = analyzer.Analyze(n.game, n.clock.Budget(n.game))
+	} else if pos, moveStats := n.player.BestMove(n.game, n.clock.Budget(n.game)); pos != engine.NoMove {
+		evaluations, stats = []engine.Evaluation{{Pos: pos, Score: float64(moveStats.Score)}}, moveStats
+	}
+	n.clock.Spend(n.game.Turn(), time.Since(startTime))
+
+	// The depth of a solved endgame is the rest of the game
+	depth := stats.Depth
+	if stats.Proven {
+		depth = n.game.Empties()
+	}
+
+	for i := 0; i < count && i < len(evaluations); i++ {
+		l.printf("search %v %.2f 0 %v", ggfMove(n.game, evaluations[i].Pos), discScore(evaluations[i], stats, n.game.Size()*n.game.Size()), depth)
+	}
+	l.println("status")
+	return nil
+}
+
+// Get the GGF name of a position, such as "F5", or "PA" for a pass
+func ggfMove(r *engine.Reversi, pos int) string {
+	if pos == engine.NoMove {
+		return "PA"
+	}
+	return strings.ToUpper(r.PosName(pos))
+}
+
+// Get the position of a GGF move, such as "F5", "f5//1.2" with its evaluation and time, or "PA" for a pass
+func parseGGFMove(r *engine.Reversi, move string) (int, error) {
+	move = strings.TrimSpace(move)
+	if slash := strings.IndexByte(move, '/'); slash >= 0 {
+		move = move[:slash]
+	}
+	if strings.EqualFold(move, "pa") || strings.EqualFold(move, "pass") {
+		return engine.NoMove, nil
+	}
+	return r.ParsePos(move)
+}
+
+// Get the game of a GGF game record: the board it starts from and the moves played from it, such as
+// (;GM[Othello]PC[NBoard]PB[a]PW[b]RE[?]TI[5:00]TY[8]BO[8 ---------------------------O*------*O--------------------------- *]B[F5]W[F6];)
+// where * is black and O is white. The other properties are ignored.
+func parseGGF(ggf string) (*engine.Reversi, error) {
+	var r *engine.Reversi
+
+	for rest := ggf; ; {
+		open := strings.IndexByte(rest, '[')
+		if open < 0 {
+			break
+		}
+		length := strings.IndexByte(rest[open:], ']')
+		if length < 0 {
+			return nil, fmt.Errorf("invalid game %q: missing ]", ggf)
+		}
+
+		// The name of the property is the upper case letters in front of its value
+		name := rest[:open]
+		start := len(name)
+		for start > 0 && name[start-1] >= 'A' && name[start-1] <= 'Z' {
+			start--
+		}
+		name = name[start:]
+		value := rest[open+1 : open+length]
+		rest = rest[open+length+1:]
+
+		switch name {
+		case "BO":
+			board, err := parseGGFBoard(value)
+			if err != nil {
+				return nil, err
+			}
+			r = board
+		case "B", "W":
+			if r == nil {
+				return nil, fmt.Errorf("invalid game %q: move before the board", ggf)
+			}
+			pos, err := parseGGFMove(r, value)
+			if err != nil {
+				return nil, err
+			}
+			color := engine.Blue
+			if name == "W" {
+				color = engine.Red
+			}
+			if err := playMove(r, color, pos); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("invalid game %q: missing the board", ggf)
+	}
+	return r, nil
+}
+
+// Get the game of a GGF board: its size, a character for each position from A1 and the color whose turn it is
+func parseGGFBoard(board string) (*engine.Reversi, error) {
+	fields := strings.Fields(board)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid board %q", board)
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid board size %q", fields[0])
+	}
+
+	// Position strings also take * for black
+	r, err := engine.ParsePosition(strings.Join(fields[1:], " "))
+	if err != nil {
+		return nil, err
+	}
+	if r.Size() != size {
+		return nil, fmt.Errorf("invalid board %q: expected %v rows of %v positions", board, size, size)
+	}
+	return r, nil
+}
@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Get an alpha-beta player without the book, searching the given number of empty positions to the end
+func newTestAlphaBeta(endgameEmpties int) *engine.AlphaBeta {
+	a := engine.NewAlphaBeta(engine.HeuristicEval)
+	a.EndgameEmpties = endgameEmpties
+	a.Rand = engine.NewRand(1)
+	return a
+}
+
+// Run an NBoard engine of the given player on the given lines of commands, and get the lines it answers
+func runNBoard(t *testing.T, player engine.Player, commands ...string) []string {
+	n, err := NewNBoard(player, engine.TimeControl{}, engine.DefaultSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := n.Run(strings.NewReader(strings.Join(commands, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+// Get the GGF game record of a game starting from the given position, without moves
+func ggfGame(r *engine.Reversi) string {
+	return fmt.Sprintf("(;GM[Othello]PC[NBoard]PB[a]PW[b]RE[?]TI[5:00]TY[8]BO[%v %v];)", r.Size(), r.Position())
+}
+
+// Get the moves and scores of the search lines of an answer, failing the test on other lines between
+// "status thinking" and "status"
+func searchLines(t *testing.T, lines []string) ([]string, []float64) {
+	var moves []string
+	var scores []float64
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "search" {
+			continue
+		}
+		if len(fields) != 5 || fields[3] != "0" {
+			t.Fatalf("invalid search line %q", line)
+		}
+		score, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			t.Fatalf("invalid score in %q", line)
+		}
+		moves = append(moves, fields[1])
+		scores = append(scores, score)
+	}
+	return moves, scores
+}
+
+func TestNBoardRun(t *testing.T) {
+	lines := runNBoard(t, newTestAlphaBeta(0),
+		"nboard 2",
+		"set game (;GM[Othello]PC[NBoard]PB[a]PW[b]RE[?]TI[5:00]TY[8]BO[8 ---------------------------O*------*O--------------------------- *]B[F5]W[D6];)",
+		"move C3/0.00/1.0",
+		"set depth 3",
+		"go",
+		"hint 3",
+		"move Z9",
+		"ping 7",
+	)
+
+	// Only nboard, go, hint, the failed move and ping answer
+	if len(lines) < 2 || lines[0] != "set myname "+EngineName || lines[1] != "status thinking" {
+		t.Fatalf("answers = %q, want the name then the thinking status", lines)
+	}
+	rest := lines[2:]
+	if strings.HasPrefix(rest[0], "nodestats ") {
+		rest = rest[1:]
+	}
+
+	// The move to the position after f5d6c3 is one of white's valid positions, with no evaluation before
+	// the endgame is solved
+	r, _ := engine.ParseTranscript("f5d6c3", engine.DefaultSize)
+	fields := strings.Split(strings.TrimPrefix(rest[0], "=== "), "/")
+	if !strings.HasPrefix(rest[0], "=== ") || len(fields) != 3 || fields[1] != "" {
+		t.Fatalf("answer to go = %q, want === <move>//<seconds>", rest[0])
+	}
+	if pos, err := parseGGFMove(r, fields[0]); err != nil || !r.IsValidPosition(pos) {
+		t.Errorf("go answers %v, which isn't a valid position of %v", fields[0], r.Position())
+	}
+	if rest[1] != "status" || rest[2] != "status thinking" {
+		t.Fatalf("answers after go = %q, want the statuses of go and hint", rest[1:])
+	}
+
+	// The hint rates 3 moves, best first, as discs searched 3 moves deep
+	moves, scores := searchLines(t, rest[3:6])
+	if len(moves) != 3 {
+		t.Fatalf("answers to hint = %q, want 3 search lines", rest[3:6])
+	}
+	for i, line := range rest[3:6] {
+		if !strings.HasSuffix(line, " 3") {
+			t.Errorf("search line %q, want the depth 3", line)
+		}
+		if math.Abs(scores[i]) >= 64 || (i > 0 && scores[i] > scores[i-1]) {
+			t.Errorf("hint scores = %v, want discs short of 64, best first", scores)
+		}
+	}
+
+	// The invalid move is reported with a status, and ping answers once the commands before it are done
+	if got := rest[6:]; len(got) != 3 || got[0] != "status" || !strings.HasPrefix(got[1], "status ") || got[2] != "pong 7" {
+		t.Errorf("last answers = %q, want the status of hint, the error of the move and pong 7", got)
+	}
+}
+
+func TestNBoardHintOfFinishedSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for searched := 0; searched < 5; {
+		r, _ := engine.NewSize(engine.DefaultSize, engine.Blue)
+		for r.Empties() > 7 && !r.IsOver() {
+			if moves := r.ValidPositions(); len(moves) > 0 {
+				r.Play(moves[rng.Intn(len(moves))])
+			} else {
+				r.Pass()
+			}
+		}
+		if r.IsOver() || len(r.ValidPositions()) < 2 {
+			continue
+		}
+		searched++
+
+		// An alpha-beta search that sees every game end rates the best move as the solver does, in discs
+		game := "set game " + ggfGame(r)
+		_, searchedScores := searchLines(t, runNBoard(t, newTestAlphaBeta(0), game, "set depth 20", "hint 1"))
+		_, solvedScores := searchLines(t, runNBoard(t, newTestAlphaBeta(engine.MaxSize*engine.MaxSize), game, "hint 1"))
+		if len(searchedScores) != 1 || len(solvedScores) != 1 || searchedScores[0] != solvedScores[0] {
+			t.Errorf("%v: the search rates the best move %v, the solver %v", r.Position(), searchedScores, solvedScores)
+		}
+		if score := searchedScores[0]; score != math.Trunc(score) || math.Abs(score) > 64 {
+			t.Errorf("%v: the search rates the best move %v, want a final disc difference", r.Position(), score)
+		}
+	}
+}
+
+func TestDiscScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		evaluation engine.Evaluation
+		stats      engine.Stats
+		discs      float64
+	}{
+		{"solved endgame", engine.Evaluation{Score: 12}, engine.Stats{Proven: true}, 12},
+		{"solved loss", engine.Evaluation{Score: -64}, engine.Stats{Proven: true}, -64},
+		{"playouts won", engine.Evaluation{Visits: 100, WinRate: 1, Score: 1}, engine.Stats{Playouts: 100}, 64},
+		{"playouts half won", engine.Evaluation{Visits: 100, WinRate: 0.5, Score: 0.5}, engine.Stats{Playouts: 100}, 0},
+		{"playouts lost", engine.Evaluation{Visits: 100, WinRate: 0.25, Score: 0.25}, engine.Stats{Playouts: 100}, -32},
+		{"game won within the search", engine.Evaluation{Score: 6000}, engine.Stats{Depth: 8}, 6},
+		{"game lost within the search", engine.Evaluation{Score: -64000}, engine.Stats{Depth: 8}, -64},
+		{"evaluation", engine.Evaluation{Score: 25}, engine.Stats{Depth: 8}, 6.25},
+		{"bad evaluation", engine.Evaluation{Score: -10}, engine.Stats{Depth: 8}, -2.5},
+		{"evaluation past the discs", engine.Evaluation{Score: 999}, engine.Stats{Depth: 8}, 63},
+		{"evaluation past the discs lost", engine.Evaluation{Score: -999}, engine.Stats{Depth: 8}, -63},
+	}
+
+	for _, test := range tests {
+		if discs := discScore(test.evaluation, test.stats, 64); discs != test.discs {
+			t.Errorf("%v: discScore = %v, want %v", test.name, discs, test.discs)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package protocol lets other programs play against the engine by exchanging lines of text with it,
+// usually over the standard input and output. It is used by the reversi command.
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"math"
+	"strings"
+)
+
+// Reads commands a line at a time and answers them, until the input ends or quit is called
+type lineReader struct {
+	scanner *bufio.Scanner
+	out     *bufio.Writer
+	quit    bool
+}
+
+// Initialize and return a reader of the commands of the given input, answering them on the given output
+func newLineReader(in io.Reader, out io.Writer) *lineReader {
+	return &lineReader{scanner: bufio.NewScanner(in), out: bufio.NewWriter(out)}
+}
+
+// Call handle with every line of the input, trimmed, flushing the answers after each line so that the
+// other program gets them right away. Blank lines are skipped.
+func (l *lineReader) run(handle func(line string) error) error {
+	for !l.quit && l.scanner.Scan() {
+		line := strings.TrimSpace(l.scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := handle(line); err != nil {
+			return err
+		}
+		if err := l.out.Flush(); err != nil {
+			return err
+		}
+	}
+	return l.scanner.Err()
+}
+
+// Write a line of output
+func (l *lineReader) println(a ...interface{}) {
+	fmt.Fprintln(l.out, a...)
+}
+
+// Write a formatted line of output
+func (l *lineReader) printf(format string, a ...interface{}) {
+	fmt.Fprintf(l.out, format+"\n", a...)
+}
+
+// Play the given position for the given color, or pass if it's NoMove. If it's the other color's turn and
+// that color has no valid position, it passes first, since protocols often leave forced passes out. That
+// pass is taken back if the move is refused.
+func playMove(r *engine.Reversi, color, pos int) error {
+	passed := false
+	if color != r.Turn() && r.Moves().IsEmpty() && !r.IsOver() {
+		r.Pass()
+		passed = true
+	}
+	err := playTurn(r, color, pos)
+	if err != nil && passed {
+		r.Undo()
+	}
+	return err
+}
+
+// Play the given position for the given color, or pass if it's NoMove, if it's the color's turn
+func playTurn(r *engine.Reversi, color, pos int) error {
+	if color != r.Turn() {
+		return fmt.Errorf("it's not the turn of %v", colorName(color))
+	}
+
+	if pos == engine.NoMove {
+		if !r.Moves().IsEmpty() {
+			return fmt.Errorf("%v can't pass with valid positions left", colorName(color))
+		}
+		r.Pass()
+		return nil
+	}
+
+	if !r.IsValidPosition(pos) {
+		return fmt.Errorf("%v is not a valid position for %v", r.PosName(pos), colorName(color))
+	}
+	r.Play(pos)
+	return nil
+}
+
+// Get the name of a color: black for blue and white for red, as in standard Othello
+func colorName(color int) string {
+	if color == engine.Blue {
+		return "black"
+	}
+	return "white"
+}
+
+// Points of an alpha-beta evaluation counted as a disc. The heuristic evaluation gives a corner 25 points,
+// which this counts as about 6 discs.
+const evalPointsPerDisc float64 = 4
+
+// Get the score of an evaluation as a disc difference for the color whose turn it is. A solved endgame
+// scores its final disc difference and playouts their win rate, stretched from -cells (every playout lost)
+// to +cells (every playout won). Alpha-beta scores the final disc difference of a game that ends within its
+// search, and otherwise the points of its evaluation, which are divided by evalPointsPerDisc and kept short
+// of -cells and +cells, since only a finished game can score those.
+func discScore(evaluation engine.Evaluation, stats engine.Stats, cells int) float64 {
+	if stats.Proven {
+		return evaluation.Score
+	}
+	if evaluation.Visits > 0 {
+		return (2*evaluation.WinRate - 1) * float64(cells)
+	}
+	if discs, ok := engine.FinalChipDifference(int(evaluation.Score)); ok {
+		return float64(discs)
+	}
+
+	limit := float64(cells - 1)
+	return math.Max(-limit, math.Min(limit, evaluation.Score/evalPointsPerDisc))
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"time"
+)
+
+// Games played elsewhere, such as by reversiSimulation, that WebSocket clients can watch as they are played.
+// The games are given to the broadcast with Update after every move, and clients can't play moves in them.
+type Broadcast struct {
+	server  *Server
+	session *session
+}
+
+// Start a broadcast of the given game, and the ones that follow it, between the given players, such as
+// "flat vs mcts". The broadcast is watched through /api/games/{id}/ws with the id of the broadcast, and it
+// doesn't expire until it is closed.
+func (s *Server) NewBroadcast(game *engine.Reversi, players string) (*Broadcast, error) {
+	session, err := s.sessions.add(game.Copy(), nil, players)
+	if err != nil {
+		return nil, err
+	}
+	session.mu.Lock()
+	session.live = true
+	session.mu.Unlock()
+	return &Broadcast{server: s, session: session}, nil
+}
+
+// Get the id of the broadcast's game in the API
+func (b *Broadcast) ID() string {
+	return b.session.id
+}
+
+// Show the given game to the clients. The moves played since the last update are sent to them, or the whole
+// game if it's a new one.
+func (b *Broadcast) Update(r *engine.Reversi) {
+	s := b.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+
+	// Replay the new moves, as long as the game goes on from the last update
+	shown := s.game.PlayedMoves()
+	moves := r.PlayedMoves()
+	continues := len(moves) >= len(shown)
+	for i := 0; continues && i < len(shown); i++ {
+		continues = moves[i].Pos == shown[i].Pos && moves[i].Color == shown[i].Color
+	}
+	if continues {
+		game := s.game.Copy()
+		for _, move := range moves[len(shown):] {
+			replay(game, move)
+		}
+		continues = game.Position() == r.Position()
+	}
+
+	if !continues {
+		s.game = r.Copy()
+		state := s.stateLocked()
+		s.feed.send(Event{Type: "state", State: &state})
+		return
+	}
+	for _, move := range moves[len(shown):] {
+		replay(s.game, move)
+		s.announceLocked()
+	}
+}
+
+// Play a move of the history of another game
+func replay(r *engine.Reversi, move engine.Move) {
+	if move.Pos == engine.NoMove {
+		r.Pass()
+	} else {
+		r.Play(move.Pos)
+	}
+}
+
+// Get a function that sends the progress of the search of the given color to the clients, for
+// engine.SetReport
+func (b *Broadcast) Report(color int) func(engine.Progress) {
+	s := b.session
+	s.mu.Lock()
+	geometry := s.game.Geometry
+	s.mu.Unlock()
+	return func(progress engine.Progress) {
+		s.feed.send(thinkingEvent(geometry, color, progress))
+	}
+}
+
+// Stop the broadcast, removing its game and disconnecting its clients
+func (b *Broadcast) Close() {
+	b.server.sessions.remove(b.session.id)
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"net/http"
+	"sync"
+)
+
+// Number of events kept for a WebSocket client that is slow to take them, after which it is dropped
+const watcherBuffer int = 64
+
+// An event of a game, sent to its WebSocket clients
+type Event struct {
+	// state when the client connects or a new game starts, move, pass, undo when moves are taken back,
+	// thinking while the computer searches, over once the game is over, or error for a message of the client
+	// that failed, sent to that client only
+	Type string `json:"type"`
+	// Color that moved, passed or is thinking
+	Color string `json:"color,omitempty"`
+	// Coordinate of the position played, and the chips it flipped
+	Move    string   `json:"move,omitempty"`
+	Flipped []string `json:"flipped,omitempty"`
+	// Progress of the computer's search
+	Thinking *Thinking `json:"thinking,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// State of the game after the event
+	State *GameState `json:"state,omitempty"`
+}
+
+// How the computer's search for its move is going
+type Thinking struct {
+	// Coordinate of the best position found so far, or empty if there is none yet
+	Best     string  `json:"best"`
+	Playouts int     `json:"playouts"`
+	Nodes    int     `json:"nodes"`
+	Depth    int     `json:"depth"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// A message of a WebSocket client
+type clientMessage struct {
+	// move to play the move of a person, or ai to play the computer's move
+	Type string `json:"type"`
+	// Coordinate of the position, such as "d3", or "pass"
+	Move string `json:"move"`
+	// Time the computer may take for its move, such as "2s"
+	MoveTime string `json:"movetime"`
+}
+
+// A WebSocket client of a game
+type watcher struct {
+	events chan Event
+	// Why the events stopped, once the channel is closed by the feed
+	reason string
+}
+
+// The WebSocket clients of a game, which are sent its events
+type feed struct {
+	mu       sync.Mutex
+	watchers map[*watcher]bool
+}
+
+// Initialize and return a feed without clients
+func newFeed() *feed {
+	return &feed{watchers: make(map[*watcher]bool)}
+}
+
+// Add a client, which is sent the given events first
+func (f *feed) watch(first ...Event) *watcher {
+	w := &watcher{events: make(chan Event, watcherBuffer+len(first))}
+	for _, event := range first {
+		w.events <- event
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.watchers[w] = true
+	return w
+}
+
+// Remove a client, if it is still there, closing its channel of events with the given reason
+func (f *feed) unwatch(w *watcher, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropLocked(w, reason)
+}
+
+// Remove a client while holding the lock
+func (f *feed) dropLocked(w *watcher, reason string) {
+	if f.watchers[w] {
+		delete(f.watchers, w)
+		w.reason = reason
+		close(w.events)
+	}
+}
+
+// Send an event to every client. Clients whose events are full are too slow to keep up, and are dropped
+// rather than holding up the game.
+func (f *feed) send(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for w := range f.watchers {
+		select {
+		case w.events <- event:
+		default:
+			f.dropLocked(w, "too slow to keep up with the game")
+		}
+	}
+}
+
+// Remove every client, closing their channels of events with the given reason
+func (f *feed) close(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for w := range f.watchers {
+		f.dropLocked(w, reason)
+	}
+}
+
+// Get the number of clients
+func (f *feed) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watchers)
+}
+
+// Get the event of the last move of a game, a move or a pass, with the state after it
+func moveEvent(r *engine.Reversi, state GameState) Event {
+	moves := r.PlayedMoves()
+	move := moves[len(moves)-1]
+	event := Event{Type: "move", Color: colorName(move.Color), Move: moveName(r, move.Pos), State: &state}
+	if move.Pos == engine.NoMove {
+		event.Type = "pass"
+	}
+	for _, pos := range move.Flipped.Positions() {
+		event.Flipped = append(event.Flipped, r.PosName(pos))
+	}
+	return event
+}
+
+// Get the event of the progress of the given color's search
+func thinkingEvent(g *engine.Geometry, color int, progress engine.Progress) Event {
+	thinking := &Thinking{
+		Playouts: progress.Playouts,
+		Nodes:    progress.Nodes,
+		Depth:    progress.Depth,
+		Seconds:  progress.Elapsed.Seconds(),
+	}
+	if progress.Best != engine.NoMove {
+		thinking.Best = g.PosName(progress.Best)
+	}
+	return Event{Type: "thinking", Color: colorName(color), Thinking: thinking}
+}
+
+// Stream the events of a game to a WebSocket client, and play the moves it sends:
+// GET /api/games/{id}/ws. The client is sent the state of the game first.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request, session *session) {
+	ws, err := upgradeWebSocket(w, r, s.settings.AllowedOrigins)
+	if err != nil {
+		return
+	}
+	watcher := session.watch()
+
+	// Send the events until the client is removed or a write fails
+	stopped := make(chan struct{})
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for event := range watcher.events {
+			if err := ws.writeJSON(event); err != nil {
+				ws.conn.Close()
+				return
+			}
+		}
+		// The feed removed the client, unless the client is leaving
+		select {
+		case <-stopped:
+		default:
+			ws.close(closeGoingAway, watcher.reason)
+		}
+	}()
+
+	for {
+		message, err := ws.readMessage()
+		if err != nil {
+			close(stopped)
+			session.feed.unwatch(watcher, "")
+			<-written
+			if closeErr, ok := err.(*closeError); ok {
+				ws.close(closeErr.code, closeErr.reason)
+			} else {
+				ws.conn.Close()
+			}
+			return
+		}
+
+		if err := s.handleClientMessage(ws, session, message); err != nil {
+			ws.writeJSON(Event{Type: "error", Error: err.Error()})
+		}
+	}
+}
+
+// Play the move of a message of a WebSocket client. The computer's move is searched in the background,
+// and its errors are sent to the client.
+func (s *Server) handleClientMessage(ws *webSocket, session *session, message []byte) error {
+	var request clientMessage
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return fmt.Errorf("invalid message: %v", err)
+	}
+
+	switch request.Type {
+	case "move":
+		_, err := session.play(request.Move)
+		return err
+	case "ai":
+		timeLimit, err := s.moveTime(request.MoveTime)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if _, err := session.playAI(timeLimit); err != nil {
+				ws.writeJSON(Event{Type: "error", Error: err.Error()})
+			}
+		}()
+		return nil
+	}
+	return fmt.Errorf("unknown message type %q, expected move or ai", request.Type)
+}
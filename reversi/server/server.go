@@ -0,0 +1,434 @@
+// Package server serves games of Reversi over HTTP, with a JSON API for creating games, playing moves and
+// asking the computer for its move, and a web page that plays them in a browser. It is used by the reversi
+// command.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Time without requests after which a game is removed, when no other time is set
+const DefaultExpiry time.Duration = 30 * time.Minute
+
+// Largest request body read, in bytes
+const maxBodySize int64 = 1 << 20
+
+// Largest settings of the computer player a request can ask for, so that no game takes more than its share of
+// the memory and processors of the server: the entries of the transposition table, the playouts and
+// iterations of the playout-based searches, and the depth of alpha-beta, which is the most empty positions a
+// game can have. Searches also use at most one worker for each processor, and solve endgames at most from the
+// number of empty positions of the server's settings or the default.
+const (
+	maxRequestTable      int = engine.DefaultTableEntries
+	maxRequestPlayouts   int = 100 * engine.Playouts
+	maxRequestIterations int = 1 << 20
+	maxRequestDepth      int = engine.MaxSize*engine.MaxSize - 4
+)
+
+// Settings of a server
+type Settings struct {
+	// Board size, color that moves first and player spec of the computer of the games that don't set them
+	Size   int
+	First  int
+	Engine string
+	// Settings of the computer players. Each game's player gets its own seed, derived from Config.Seed.
+	Config engine.PlayerConfig
+	// Time the computer takes for a move when the request doesn't set one, and the most a request can set
+	MoveTime    time.Duration
+	MaxMoveTime time.Duration
+	// Time without requests after which a game is removed. If 0, DefaultExpiry is used.
+	Expiry time.Duration
+	// Maximum number of games at the same time, or 0 for no limit
+	MaxGames int
+	// Origins of the web pages of other sites that may open WebSockets to the games, such as
+	// "https://example.com", or "*" for any. The server's own pages always may.
+	AllowedOrigins []string
+}
+
+// An HTTP server of games. Its API is:
+//
+// POST /api/games: create a game, from a JSON object with the optional size, first, start and engine of
+// the game, and answer its state
+// GET /api/games/{id}: answer the state of the game
+// DELETE /api/games/{id}: remove the game
+// POST /api/games/{id}/moves: play the move of a JSON object such as {"move": "d3"} for the current turn,
+// and answer the state of the game
+// POST /api/games/{id}/ai: play the computer's move for the current turn, searching for the time of a
+// JSON object such as {"movetime": "2s"}, and answer the move and the state of the game
+// GET /api/games/{id}/history: answer the moves played
+// POST /api/games/{id}/undo: take back the last move, or with a JSON object such as {"color": "blue"}, the
+// moves back to the last turn of that color, and answer the state of the game
+// GET /api/games/{id}/ws: upgrade to a WebSocket that is sent the events of the game, such as
+// {"type": "move", "color": "blue", "move": "d3", ...}, and takes moves such as {"type": "move", "move": "d3"}
+// or {"type": "ai", "movetime": "2s"}
+//
+// Every other path is a file of the web page that plays games against the computer, from / in a browser.
+//
+// Errors are answered with an HTTP error status and a JSON object with the error, such as
+// {"error": "a1 is not a valid position"}.
+type Server struct {
+	settings Settings
+	sessions *sessionStore
+	mux      *http.ServeMux
+	// Number of games created, which seeds their players
+	games int64
+}
+
+// Initialize and return a server with the given settings. Close stops it from expiring its games.
+func NewServer(settings Settings) (*Server, error) {
+	if settings.Expiry == 0 {
+		settings.Expiry = DefaultExpiry
+	}
+	if settings.MoveTime == 0 {
+		settings.MoveTime = engine.DefaultMoveTime
+	}
+	if settings.MaxMoveTime < settings.MoveTime {
+		settings.MaxMoveTime = settings.MoveTime
+	}
+	if _, err := engine.GeometryOf(settings.Size); err != nil {
+		return nil, err
+	}
+	if _, err := engine.NewPlayer(settings.Engine, settings.Config); err != nil {
+		return nil, err
+	}
+
+	s := &Server{settings: settings, sessions: newSessionStore(settings.MaxGames, settings.Expiry), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/api/games", s.handleGames)
+	s.mux.HandleFunc("/api/games/", s.handleGame)
+	s.mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, statusErrorf(http.StatusNotFound, "not found"))
+	})
+	s.mux.Handle("/", webHandler())
+	return s, nil
+}
+
+// Stop expiring games
+func (s *Server) Close() {
+	s.sessions.close()
+}
+
+// Answer a request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// An error answered with the given HTTP status
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// Get an error answered with the given HTTP status
+func statusErrorf(status int, format string, a ...interface{}) error {
+	return &statusError{status: status, err: fmt.Errorf(format, a...)}
+}
+
+// Answer the given value as JSON with the given HTTP status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// Answer an error as JSON, with its HTTP status if it has one, otherwise as a bad request
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Read the JSON body of a request into the given value. An empty body leaves the value as it is.
+func readJSON(w http.ResponseWriter, r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// Answer that the request's method is not allowed, listing the allowed ones
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, statusErrorf(http.StatusMethodNotAllowed, "method not allowed, expected %v", strings.Join(allowed, " or ")))
+}
+
+// Settings of a new game, all of them optional
+type newGameRequest struct {
+	Size int `json:"size"`
+	// Color that moves first: blue or red
+	First string `json:"first"`
+	// Transcript or position string to start from, instead of the four starting chips
+	Start string `json:"start"`
+	// Player spec of the computer, such as "alphabeta:depth=6"
+	Engine string `json:"engine"`
+}
+
+// Create a game: POST /api/games
+func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	request := newGameRequest{Size: s.settings.Size, Engine: s.settings.Engine}
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	session, err := s.newSession(request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, session.state())
+}
+
+// Create a session for a new game with the given settings
+func (s *Server) newSession(request newGameRequest) (*session, error) {
+	first := s.settings.First
+	if request.First != "" {
+		var err error
+		if first, err = parseColor(request.First); err != nil {
+			return nil, err
+		}
+	}
+
+	game, err := engine.NewSize(request.Size, first)
+	if err != nil {
+		return nil, err
+	}
+	if request.Start != "" {
+		if game, err = engine.Parse(request.Start, request.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// The server's own player is trusted, the players of requests are limited
+	if request.Engine != s.settings.Engine {
+		if err := s.checkEngine(request.Engine); err != nil {
+			return nil, err
+		}
+	}
+
+	config := s.settings.Config
+	config.Seed = engine.DeriveSeed(config.Seed, atomic.AddInt64(&s.games, 1))
+	player, err := engine.NewPlayer(request.Engine, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sessions.add(game, player, request.Engine)
+}
+
+// Check that the settings of a player spec of a request are within what a game can use
+func (s *Server) checkEngine(spec string) error {
+	endgame := engine.DefaultEndgameEmpties
+	if s.settings.Config.EndgameEmpties > endgame {
+		endgame = s.settings.Config.EndgameEmpties
+	}
+	limits := map[string]int{
+		"table":      maxRequestTable,
+		"playouts":   maxRequestPlayouts,
+		"iterations": maxRequestIterations,
+		"depth":      maxRequestDepth,
+		"workers":    runtime.NumCPU(),
+		"endgame":    endgame,
+	}
+
+	_, settings := engine.SplitPlayerSpec(spec)
+	for _, setting := range settings {
+		keyValue := strings.SplitN(setting, "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		limit, ok := limits[keyValue[0]]
+		// Settings that aren't numbers are left to engine.NewPlayer to reject
+		value, err := strconv.Atoi(keyValue[1])
+		if ok && err == nil && value > limit {
+			return fmt.Errorf("invalid setting %q of player %q: the most a game can use is %v", setting, spec, limit)
+		}
+	}
+	return nil
+}
+
+// Answer a request about a game: /api/games/{id} and the paths under it
+func (s *Server) handleGame(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/games/"), "/"), "/")
+	if len(parts) > 2 {
+		writeError(w, statusErrorf(http.StatusNotFound, "not found"))
+		return
+	}
+
+	id := parts[0]
+	session := s.sessions.get(id)
+	if session == nil {
+		writeError(w, statusErrorf(http.StatusNotFound, "no game %q, it may have expired", id))
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch action {
+	case "":
+		s.handleState(w, r, session)
+	case "moves":
+		s.handleMove(w, r, session)
+	case "ai":
+		s.handleAI(w, r, session)
+	case "history":
+		s.handleHistory(w, r, session)
+	case "undo":
+		s.handleUndo(w, r, session)
+	case "ws":
+		s.handleWebSocket(w, r, session)
+	default:
+		writeError(w, statusErrorf(http.StatusNotFound, "not found"))
+	}
+}
+
+// Answer the state of a game, or remove it: GET or DELETE /api/games/{id}
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request, session *session) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, session.state())
+	case http.MethodDelete:
+		s.sessions.remove(session.id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// A move played by a person
+type moveRequest struct {
+	// Coordinate of the position, such as "d3", or "pass"
+	Move string `json:"move"`
+}
+
+// Play a move for the current turn: POST /api/games/{id}/moves
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request, session *session) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var request moveRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	state, err := session.play(request.Move)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// The time the computer may take for its move
+type aiRequest struct {
+	// Duration such as "2s" or "500ms"
+	MoveTime string `json:"movetime"`
+}
+
+// Play the computer's move for the current turn: POST /api/games/{id}/ai
+func (s *Server) handleAI(w http.ResponseWriter, r *http.Request, session *session) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var request aiRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	timeLimit, err := s.moveTime(request.MoveTime)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	move, err := session.playAI(timeLimit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, move)
+}
+
+// Get the time the computer may take for a move from a duration such as "2s", or the default time if it's
+// empty, at most the maximum time
+func (s *Server) moveTime(duration string) (time.Duration, error) {
+	timeLimit := s.settings.MoveTime
+	if duration != "" {
+		var err error
+		if timeLimit, err = time.ParseDuration(duration); err != nil || timeLimit <= 0 {
+			return 0, fmt.Errorf("invalid movetime %q, expected a duration such as 2s", duration)
+		}
+	}
+	if timeLimit > s.settings.MaxMoveTime {
+		timeLimit = s.settings.MaxMoveTime
+	}
+	return timeLimit, nil
+}
+
+// The moves to take back
+type undoRequest struct {
+	// Color whose last turn to go back to, blue or red, or empty to take back one move
+	Color string `json:"color"`
+}
+
+// Take back moves: POST /api/games/{id}/undo
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request, session *session) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var request undoRequest
+	if err := readJSON(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	state, err := session.undo(request.Color)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// Answer the moves played in a game: GET /api/games/{id}/history
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request, session *session) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": session.id, "moves": session.history()})
+}
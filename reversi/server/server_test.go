@@ -0,0 +1,302 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Start a server with the given settings, stopped at the end of the test, playing alpha-beta 2 moves deep
+// on the standard board unless the settings say otherwise
+func newTestServer(t *testing.T, settings Settings) (*Server, *httptest.Server) {
+	if settings.Size == 0 {
+		settings.Size = engine.DefaultSize
+	}
+	if settings.Engine == "" {
+		settings.Engine = "alphabeta:depth=2"
+	}
+	if settings.First == 0 {
+		settings.First = engine.Blue
+	}
+	settings.Config.Seed = 1
+	s, err := NewServer(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return s, ts
+}
+
+// Send a request with the given JSON body, which may be empty, and decode the JSON answer into the given
+// value if it isn't nil. Get the status of the answer.
+func send(ts *httptest.Server, method, path, body string, answer interface{}) (int, error) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if answer != nil {
+		if err := json.NewDecoder(resp.Body).Decode(answer); err != nil {
+			return resp.StatusCode, fmt.Errorf("%v %v: decoding the answer: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// Send a request as send does, failing the test if it can't be sent
+func request(t *testing.T, ts *httptest.Server, method, path, body string, answer interface{}) int {
+	status, err := send(ts, method, path, body, answer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// Create a game from the given JSON settings and get its state, failing the test if it isn't created
+func createGame(t *testing.T, ts *httptest.Server, body string) GameState {
+	var state GameState
+	if status := request(t, ts, http.MethodPost, "/api/games", body, &state); status != http.StatusCreated {
+		t.Fatalf("creating a game with %v: status %v", body, status)
+	}
+	return state
+}
+
+func TestServerGame(t *testing.T) {
+	_, ts := newTestServer(t, Settings{})
+
+	created := createGame(t, ts, `{"size": 6}`)
+	if created.ID == "" || created.Size != 6 || created.Turn != "blue" || created.Moves != 0 || created.Engine != "alphabeta:depth=2" {
+		t.Fatalf("created game = %+v, want a new 6x6 game with blue to move", created)
+	}
+	game := "/api/games/" + created.ID
+
+	var state GameState
+	if status := request(t, ts, http.MethodGet, game, "", &state); status != http.StatusOK || state.Position != created.Position {
+		t.Errorf("state = %v %+v, want the created game", status, state)
+	}
+
+	// A valid move is played, an invalid one is refused
+	if status := request(t, ts, http.MethodPost, game+"/moves", `{"move": "e4"}`, &state); status != http.StatusOK {
+		t.Fatalf("playing e4: status %v", status)
+	}
+	if state.Turn != "red" || state.BlueScore != 4 || state.RedScore != 1 || state.Board[3] != "--XXX-" {
+		t.Errorf("state after e4 = %+v", state)
+	}
+	var failure map[string]string
+	if status := request(t, ts, http.MethodPost, game+"/moves", `{"move": "a1"}`, &failure); status != http.StatusConflict || failure["error"] == "" {
+		t.Errorf("playing a1: status %v, %v, want 409 with an error", status, failure)
+	}
+	if status := request(t, ts, http.MethodPost, game+"/moves", `{"move": "z9"}`, nil); status != http.StatusBadRequest {
+		t.Errorf("playing z9: status %v, want 400", status)
+	}
+
+	// The computer plays red's move
+	var move AIMove
+	if status := request(t, ts, http.MethodPost, game+"/ai", `{"movetime": "1s"}`, &move); status != http.StatusOK {
+		t.Fatalf("AI move: status %v", status)
+	}
+	if move.Color != "red" || move.Depth != 2 || move.State.Turn != "blue" || move.State.Moves != 2 {
+		t.Errorf("AI move = %+v, want red's move 2 moves deep", move)
+	}
+
+	var history struct {
+		ID    string        `json:"id"`
+		Moves []HistoryMove `json:"moves"`
+	}
+	if status := request(t, ts, http.MethodGet, game+"/history", "", &history); status != http.StatusOK {
+		t.Fatalf("history: status %v", status)
+	}
+	if len(history.Moves) != 2 || history.Moves[0].Move != "e4" || history.Moves[0].Color != "blue" || strings.Join(history.Moves[0].Flipped, " ") != "d4" ||
+		history.Moves[1].Move != move.Move || history.Moves[1].Number != 2 {
+		t.Errorf("history = %+v, want e4 flipping d4 then %v", history, move.Move)
+	}
+
+	// Undoing to blue's turn takes both moves back, and then there is nothing left to undo
+	if status := request(t, ts, http.MethodPost, game+"/undo", `{"color": "blue"}`, &state); status != http.StatusOK || state.Position != created.Position || state.Moves != 0 {
+		t.Errorf("undo to blue's turn: status %v, %+v, want the created game", status, state)
+	}
+	if status := request(t, ts, http.MethodPost, game+"/undo", "", nil); status != http.StatusConflict {
+		t.Errorf("undo without moves: status %v, want 409", status)
+	}
+
+	// Methods the paths don't take, and a game removed
+	if status := request(t, ts, http.MethodGet, game+"/moves", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET of the moves: status %v, want 405", status)
+	}
+	if status := request(t, ts, http.MethodDelete, game, "", nil); status != http.StatusNoContent {
+		t.Errorf("removing the game: status %v, want 204", status)
+	}
+	if status := request(t, ts, http.MethodGet, game, "", nil); status != http.StatusNotFound {
+		t.Errorf("state of the removed game: status %v, want 404", status)
+	}
+}
+
+func TestServerCreateGame(t *testing.T) {
+	_, ts := newTestServer(t, Settings{MaxGames: 3})
+
+	if state := createGame(t, ts, ""); state.Size != engine.DefaultSize || state.Turn != "blue" {
+		t.Errorf("game with the server's settings = %+v", state)
+	}
+	if state := createGame(t, ts, `{"first": "red", "engine": "random"}`); state.Turn != "red" || state.Engine != "random" {
+		t.Errorf("game with red first and a random player = %+v", state)
+	}
+	if state := createGame(t, ts, `{"start": "f5d6"}`); state.Moves != 2 || state.Transcript != "f5d6" {
+		t.Errorf("game from f5d6 = %+v", state)
+	}
+
+	for _, body := range []string{`{"size": 5}`, `{"first": "green"}`, `{"start": "a1"}`, `{"engine": "mcts:workers=1000"}`, `{"color": "blue"}`, `{`} {
+		if status := request(t, ts, http.MethodPost, "/api/games", body, nil); status != http.StatusBadRequest {
+			t.Errorf("creating a game with %v: status %v, want 400", body, status)
+		}
+	}
+	if status := request(t, ts, http.MethodPost, "/api/games", "", nil); status != http.StatusServiceUnavailable {
+		t.Errorf("creating more games than the server keeps: status %v, want 503", status)
+	}
+}
+
+// A player that waits for its move until it is released
+type blockingPlayer struct {
+	// Closed to play the first valid position
+	release chan struct{}
+}
+
+func (p *blockingPlayer) BestMove(r *engine.Reversi, timeLimit time.Duration) (int, engine.Stats) {
+	<-p.release
+	return r.ValidPositions()[0], engine.Stats{}
+}
+
+func TestServerConflictWhileThinking(t *testing.T) {
+	s, ts := newTestServer(t, Settings{})
+	created := createGame(t, ts, "")
+	game := "/api/games/" + created.ID
+	player := &blockingPlayer{release: make(chan struct{})}
+	s.sessions.get(created.ID).player = player
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var move AIMove
+	var aiStatus int
+	var aiErr error
+	go func() {
+		defer wg.Done()
+		aiStatus, aiErr = send(ts, http.MethodPost, game+"/ai", "", &move)
+	}()
+
+	// Wait for the search to start
+	var state GameState
+	for start := time.Now(); !state.Thinking; {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("the computer never started thinking")
+		}
+		request(t, ts, http.MethodGet, game, "", &state)
+		runtime.Gosched()
+	}
+
+	// Nothing can change the game while the computer is thinking, from any number of requests at once
+	var conflicts sync.WaitGroup
+	statuses := make([]int, 12)
+	for i := range statuses {
+		conflicts.Add(1)
+		go func(i int) {
+			defer conflicts.Done()
+			switch i % 3 {
+			case 0:
+				statuses[i], _ = send(ts, http.MethodPost, game+"/moves", `{"move": "d3"}`, nil)
+			case 1:
+				statuses[i], _ = send(ts, http.MethodPost, game+"/ai", "", nil)
+			default:
+				statuses[i], _ = send(ts, http.MethodPost, game+"/undo", "", nil)
+			}
+		}(i)
+	}
+	conflicts.Wait()
+	for i, status := range statuses {
+		if status != http.StatusConflict {
+			t.Errorf("request %v while the computer is thinking: status %v, want 409", i, status)
+		}
+	}
+
+	close(player.release)
+	wg.Wait()
+	if aiErr != nil {
+		t.Fatal(aiErr)
+	}
+	if aiStatus != http.StatusOK || move.State.Moves != 1 || move.State.Thinking {
+		t.Errorf("AI move: status %v, %+v, want the first move played", aiStatus, move)
+	}
+	if status := request(t, ts, http.MethodPost, game+"/undo", "", nil); status != http.StatusOK {
+		t.Errorf("undo after the AI move: status %v, want 200", status)
+	}
+}
+
+func TestServerExpiry(t *testing.T) {
+	s, ts := newTestServer(t, Settings{Expiry: 10 * time.Millisecond})
+	expired := createGame(t, ts, "")
+	kept := createGame(t, ts, "")
+	player := &blockingPlayer{release: make(chan struct{})}
+	s.sessions.get(kept.ID).player = player
+	defer close(player.release)
+
+	// The game the computer is thinking about is kept, the other one is removed once the sweep runs
+	go send(ts, http.MethodPost, "/api/games/"+kept.ID+"/ai", "", nil)
+	// Every request uses the game again, so the next one waits for longer than the expiry
+	for start := time.Now(); request(t, ts, http.MethodGet, "/api/games/"+expired.ID, "", nil) != http.StatusNotFound; time.Sleep(50 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("the game never expired")
+		}
+	}
+	if status := request(t, ts, http.MethodGet, "/api/games/"+kept.ID, "", nil); status != http.StatusOK {
+		t.Errorf("state of the game the computer is thinking about: status %v, want 200", status)
+	}
+}
+
+func TestCheckEngine(t *testing.T) {
+	s, _ := newTestServer(t, Settings{})
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"mcts", true},
+		{fmt.Sprintf("flat:playouts=%v", maxRequestPlayouts), true},
+		{fmt.Sprintf("flat:playouts=%v", maxRequestPlayouts+1), false},
+		{fmt.Sprintf("mcts:iterations=%v", maxRequestIterations+1), false},
+		{fmt.Sprintf("mcts:workers=%v", runtime.NumCPU()), true},
+		{fmt.Sprintf("mcts:workers=%v", runtime.NumCPU()+1), false},
+		{fmt.Sprintf("alphabeta:table=%v", maxRequestTable), true},
+		{fmt.Sprintf("alphabeta:table=%v", maxRequestTable+1), false},
+		{fmt.Sprintf("alphabeta:depth=%v", maxRequestDepth), true},
+		{fmt.Sprintf("alphabeta:depth=%v", maxRequestDepth+1), false},
+		{fmt.Sprintf("alphabeta:endgame=%v", engine.DefaultEndgameEmpties), true},
+		{fmt.Sprintf("alphabeta:endgame=%v", engine.DefaultEndgameEmpties+1), false},
+		// Settings that aren't numbers are left to the player
+		{"alphabeta:eval=chips", true},
+		{"alphabeta:depth=deep", true},
+	}
+
+	for _, test := range tests {
+		if err := s.checkEngine(test.spec); (err == nil) != test.ok {
+			t.Errorf("checkEngine(%q) = %v, want ok %v", test.spec, err, test.ok)
+		}
+	}
+
+	// A server that solves larger endgames lets its games do the same
+	s, _ = newTestServer(t, Settings{Config: engine.PlayerConfig{EndgameEmpties: 20}})
+	if err := s.checkEngine("alphabeta:endgame=20"); err != nil {
+		t.Errorf("checkEngine of the server's endgame: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A game played through the API: the game, the computer player that plays its AI moves, the WebSocket
+// clients it sends its events to, and when it was last used. Requests to the same session are safe to make
+// at the same time.
+type session struct {
+	id string
+	// Player spec of the computer player
+	engineSpec string
+	feed       *feed
+	// Whether the game is played elsewhere and only watched through the API, as by a Broadcast
+	live bool
+
+	mu     sync.Mutex
+	game   *engine.Reversi
+	player engine.Player
+	// Whether the computer player is searching, in which case the game can't change
+	thinking bool
+	lastUsed time.Time
+}
+
+// The sessions of a server, which expire after a time without requests
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	// Maximum number of sessions at the same time
+	max    int
+	expiry time.Duration
+	// Closed to stop expiring sessions
+	done chan struct{}
+}
+
+// Initialize and return a store of at most max sessions, which expire after the given time without requests,
+// and start removing the expired ones
+func newSessionStore(max int, expiry time.Duration) *sessionStore {
+	s := &sessionStore{sessions: make(map[string]*session), max: max, expiry: expiry, done: make(chan struct{})}
+	go s.expireLoop()
+	return s
+}
+
+// Get a new random session id
+func newSessionID() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Add a new session with the given game and player
+func (s *sessionStore) add(game *engine.Reversi, player engine.Player, engineSpec string) (*session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.max > 0 && len(s.sessions) >= s.max {
+		return nil, statusErrorf(http.StatusServiceUnavailable, "too many games, try again later")
+	}
+
+	session := &session{id: id, engineSpec: engineSpec, feed: newFeed(), game: game, player: player, lastUsed: time.Now()}
+	s.sessions[id] = session
+	return session, nil
+}
+
+// Get the session with the given id, or nil if there is none or it expired. Getting a session counts as using it.
+func (s *sessionStore) get(id string) *session {
+	s.mu.Lock()
+	session, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	session.mu.Lock()
+	session.lastUsed = time.Now()
+	session.mu.Unlock()
+	return session
+}
+
+// Remove the session with the given id, disconnecting its WebSocket clients, and return whether there was one
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if ok {
+		delete(s.sessions, id)
+		session.feed.close("the game was removed")
+	}
+	return ok
+}
+
+// Remove the sessions that weren't used for longer than the expiry time, except while the computer is
+// searching their move, while WebSocket clients are connected, or while they are played elsewhere
+func (s *sessionStore) expire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		session.mu.Lock()
+		if !session.thinking && !session.live && session.feed.len() == 0 && now.Sub(session.lastUsed) > s.expiry {
+			delete(s.sessions, id)
+		}
+		session.mu.Unlock()
+	}
+}
+
+// Check for expired sessions a few times per expiry time, until the store is closed
+func (s *sessionStore) expireLoop() {
+	ticker := time.NewTicker(s.expiry/4 + time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.expire(now)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Stop expiring sessions
+func (s *sessionStore) close() {
+	close(s.done)
+}
+
+// Get the state of the session's game
+func (s *session) state() GameState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stateLocked()
+}
+
+// Get the state of the session's game, while holding its lock
+func (s *session) stateLocked() GameState {
+	state := gameState(s.game)
+	state.ID = s.id
+	state.Engine = s.engineSpec
+	state.Thinking = s.thinking
+	return state
+}
+
+// Add a WebSocket client, which is sent the state of the game first
+func (s *session) watch() *watcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.stateLocked()
+	return s.feed.watch(Event{Type: "state", State: &state})
+}
+
+// Send the last move of the game to the WebSocket clients, and the end of the game if it ended it, while
+// holding the lock
+func (s *session) announceLocked() {
+	state := s.stateLocked()
+	s.feed.send(moveEvent(s.game, state))
+	if state.Over {
+		s.feed.send(Event{Type: "over", State: &state})
+	}
+}
+
+// Play the given move for the current turn: a coordinate, or "pass" when there is no valid position
+func (s *session) play(move string) (GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.live {
+		return s.stateLocked(), statusErrorf(http.StatusConflict, "the game is played elsewhere, it can only be watched")
+	}
+	if s.thinking {
+		return s.stateLocked(), statusErrorf(http.StatusConflict, "the computer is thinking about its move")
+	}
+	if err := playMove(s.game, move); err != nil {
+		return s.stateLocked(), err
+	}
+	s.announceLocked()
+	return s.stateLocked(), nil
+}
+
+// Take back the last move, or if a color is given, every move back to the last turn of that color, so that
+// a person playing against the computer gets their turn back
+func (s *session) undo(colorName string) (GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.live {
+		return s.stateLocked(), statusErrorf(http.StatusConflict, "the game is played elsewhere, it can only be watched")
+	}
+	if s.thinking {
+		return s.stateLocked(), statusErrorf(http.StatusConflict, "the computer is thinking about its move")
+	}
+	color := engine.Empty
+	if colorName != "" {
+		var err error
+		if color, err = parseColor(colorName); err != nil {
+			return s.stateLocked(), err
+		}
+	}
+
+	if _, ok := s.game.Undo(); !ok {
+		return s.stateLocked(), statusErrorf(http.StatusConflict, "there is no move to take back")
+	}
+	for color != engine.Empty && s.game.Turn() != color {
+		if _, ok := s.game.Undo(); !ok {
+			break
+		}
+	}
+
+	state := s.stateLocked()
+	s.feed.send(Event{Type: "undo", State: &state})
+	return state, nil
+}
+
+// Play the computer's move for the current turn, searching for at most the given time. The session is not
+// locked during the search, so its state can be read, but it can't change until the move is played. The
+// WebSocket clients are sent the progress of the search.
+func (s *session) playAI(timeLimit time.Duration) (AIMove, error) {
+	s.mu.Lock()
+	if s.live {
+		state := s.stateLocked()
+		s.mu.Unlock()
+		return AIMove{State: state}, statusErrorf(http.StatusConflict, "the game is played elsewhere, it can only be watched")
+	}
+	if s.thinking {
+		s.mu.Unlock()
+		return AIMove{}, statusErrorf(http.StatusConflict, "the computer is already thinking about its move")
+	}
+	if s.game.IsOver() {
+		state := s.stateLocked()
+		s.mu.Unlock()
+		return AIMove{State: state}, statusErrorf(http.StatusConflict, "the game is over")
+	}
+	s.thinking = true
+	game := s.game.Copy()
+	state := s.stateLocked()
+	s.feed.send(Event{Type: "thinking", Color: state.Turn, Thinking: &Thinking{}, State: &state})
+	s.mu.Unlock()
+
+	color := game.Turn()
+	engine.SetReport(s.player, func(progress engine.Progress) {
+		s.feed.send(thinkingEvent(game.Geometry, color, progress))
+	})
+	pos, stats := s.player.BestMove(game, timeLimit)
+	engine.SetReport(s.player, nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.thinking = false
+	s.lastUsed = time.Now()
+
+	if pos == engine.NoMove {
+		s.game.Pass()
+	} else {
+		s.game.Play(pos)
+	}
+	s.announceLocked()
+
+	move := aiMove(game, pos, stats)
+	move.State = s.stateLocked()
+	return move, nil
+}
+
+// Get the moves played in the session's game
+func (s *session) history() []HistoryMove {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return history(s.game)
+}
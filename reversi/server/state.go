@@ -0,0 +1,198 @@
+package server
+
+import (
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"net/http"
+	"strings"
+)
+
+// The state of a game, as answered by the API
+type GameState struct {
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+	// Rows of the board from row 1, each with a character per column from a: X for blue, O for red and
+	// - for empty positions
+	Board []string `json:"board"`
+	// Color whose turn it is, blue or red, or empty once the game is over
+	Turn string `json:"turn"`
+	// Valid positions of the current turn, or "pass" if it has none
+	ValidMoves []string `json:"valid_moves"`
+	BlueScore  int      `json:"blue_score"`
+	RedScore   int      `json:"red_score"`
+	Over       bool     `json:"over"`
+	// Winner once the game is over: blue, red or tie
+	Winner string `json:"winner,omitempty"`
+	// Number of moves played, passes included
+	Moves int `json:"moves"`
+	// Position string of the game, and its transcript if it started from the four starting chips
+	Position   string `json:"position"`
+	Transcript string `json:"transcript,omitempty"`
+	// Player spec of the computer
+	Engine string `json:"engine"`
+	// Whether the computer is searching for its move
+	Thinking bool `json:"thinking"`
+}
+
+// A move played by the computer, and how it found it
+type AIMove struct {
+	// Coordinate of the position played, or "pass"
+	Move  string `json:"move"`
+	Color string `json:"color"`
+	// Whether the move came from the opening book
+	Book bool `json:"book"`
+	// Whether the endgame was solved, and the final chip difference for the color that moved if it was
+	Proven bool `json:"proven"`
+	Score  int  `json:"score"`
+	// Depth of the alpha-beta search, playouts of the playout-based searches and positions searched
+	Depth    int     `json:"depth"`
+	Playouts int     `json:"playouts"`
+	Nodes    int     `json:"nodes"`
+	Seconds  float64 `json:"seconds"`
+	// State of the game after the move
+	State GameState `json:"state"`
+}
+
+// A move of the history of a game
+type HistoryMove struct {
+	// Number of the move from 1, passes included
+	Number int    `json:"number"`
+	Color  string `json:"color"`
+	// Coordinate of the position played, or "pass"
+	Move string `json:"move"`
+	// Coordinates of the chips the move flipped
+	Flipped []string `json:"flipped"`
+}
+
+// Get the name of a color: blue or red
+func colorName(color int) string {
+	if color == engine.Blue {
+		return "blue"
+	}
+	return "red"
+}
+
+// Get the color of a name: blue or red, or b or r
+func parseColor(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "b", "blue":
+		return engine.Blue, nil
+	case "r", "red":
+		return engine.Red, nil
+	}
+	return engine.Empty, fmt.Errorf("unknown color %q, expected blue or red", name)
+}
+
+// Get the name of a move: the coordinate of its position, or "pass"
+func moveName(r *engine.Reversi, pos int) string {
+	if pos == engine.NoMove {
+		return "pass"
+	}
+	return r.PosName(pos)
+}
+
+// Get the state of a game
+func gameState(r *engine.Reversi) GameState {
+	state := GameState{
+		Size:       r.Size(),
+		BlueScore:  r.Score(engine.Blue),
+		RedScore:   r.Score(engine.Red),
+		Over:       r.IsOver(),
+		Moves:      len(r.PlayedMoves()),
+		Position:   r.Position(),
+		ValidMoves: []string{},
+	}
+	if r.FromStart() {
+		state.Transcript = r.Transcript()
+	}
+
+	board := r.Board()
+	for row := 0; row < r.Size(); row++ {
+		var line strings.Builder
+		for _, chip := range board[row*r.Size() : (row+1)*r.Size()] {
+			switch chip {
+			case engine.Blue:
+				line.WriteByte('X')
+			case engine.Red:
+				line.WriteByte('O')
+			default:
+				line.WriteByte('-')
+			}
+		}
+		state.Board = append(state.Board, line.String())
+	}
+
+	if state.Over {
+		switch r.CheckWin(true) {
+		case engine.Blue:
+			state.Winner = "blue"
+		case engine.Red:
+			state.Winner = "red"
+		default:
+			state.Winner = "tie"
+		}
+		return state
+	}
+
+	state.Turn = colorName(r.Turn())
+	for _, pos := range r.ValidPositions() {
+		state.ValidMoves = append(state.ValidMoves, r.PosName(pos))
+	}
+	if len(state.ValidMoves) == 0 {
+		state.ValidMoves = []string{"pass"}
+	}
+	return state
+}
+
+// Get the computer's move, found with the given stats in the given game, before it was played
+func aiMove(r *engine.Reversi, pos int, stats engine.Stats) AIMove {
+	return AIMove{
+		Move:     moveName(r, pos),
+		Color:    colorName(r.Turn()),
+		Book:     stats.Book,
+		Proven:   stats.Proven,
+		Score:    stats.Score,
+		Depth:    stats.Depth,
+		Playouts: stats.Playouts,
+		Nodes:    stats.Nodes,
+		Seconds:  stats.Elapsed.Seconds(),
+	}
+}
+
+// Get the moves played in a game
+func history(r *engine.Reversi) []HistoryMove {
+	moves := []HistoryMove{}
+	for i, move := range r.PlayedMoves() {
+		var flipped []string
+		for _, pos := range move.Flipped.Positions() {
+			flipped = append(flipped, r.PosName(pos))
+		}
+		moves = append(moves, HistoryMove{Number: i + 1, Color: colorName(move.Color), Move: moveName(r, move.Pos), Flipped: flipped})
+	}
+	return moves
+}
+
+// Play a move for the current turn: a coordinate, or "pass" when the turn has no valid position
+func playMove(r *engine.Reversi, move string) error {
+	if r.IsOver() {
+		return statusErrorf(http.StatusConflict, "the game is over")
+	}
+
+	if strings.EqualFold(strings.TrimSpace(move), "pass") {
+		if !r.Moves().IsEmpty() {
+			return statusErrorf(http.StatusConflict, "%v can't pass with valid positions left", colorName(r.Turn()))
+		}
+		r.Pass()
+		return nil
+	}
+
+	pos, err := r.ParsePos(move)
+	if err != nil {
+		return err
+	}
+	if !r.IsValidPosition(pos) {
+		return statusErrorf(http.StatusConflict, "%v is not a valid position for %v", r.PosName(pos), colorName(r.Turn()))
+	}
+	r.Play(pos)
+	return nil
+}
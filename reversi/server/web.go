@@ -0,0 +1,22 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Files of the web page, built into the program so that it works without any other files or network access
+//
+//go:embed web
+var webFiles embed.FS
+
+// Get the handler that serves the files of the web page
+func webHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// The directory is always embedded
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
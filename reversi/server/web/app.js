@@ -0,0 +1,310 @@
+// Plays a game against the computer through the HTTP API of the server, and follows the computer's search
+// through the game's WebSocket. With #watch=<id> in the address, it only watches that game, such as one
+// played by reversiSimulation.
+"use strict";
+
+const elements = {
+  board: document.getElementById("board"),
+  status: document.getElementById("status"),
+  thinking: document.getElementById("thinking"),
+  error: document.getElementById("error"),
+  undo: document.getElementById("undo"),
+  pass: document.getElementById("pass"),
+  form: document.getElementById("new-game"),
+  moves: document.getElementById("moves"),
+  blueName: document.getElementById("blue-name"),
+  redName: document.getElementById("red-name"),
+  blueScore: document.getElementById("blue-score"),
+  redScore: document.getElementById("red-score"),
+};
+
+// State of the game as answered by the API, the moves played, and the settings of the game
+let game = null;
+let history = [];
+let human = "blue";
+let moveTime = "";
+// Whether a request is waiting for its answer, in which case the board takes no clicks
+let busy = false;
+// Whether the computer is searching, as its WebSocket events say, and the best position it found so far
+let searching = false;
+let considered = "";
+let socket = null;
+let watching = false;
+
+// Send a request to the API and return the JSON answer, or throw the error it answers
+async function request(method, path, body) {
+  const options = { method: method };
+  if (body !== undefined) {
+    options.headers = { "Content-Type": "application/json" };
+    options.body = JSON.stringify(body);
+  }
+  const response = await fetch(path, options);
+  const answer = response.status === 204 ? null : await response.json();
+  if (!response.ok) {
+    throw new Error(answer && answer.error ? answer.error : response.statusText);
+  }
+  return answer;
+}
+
+// Get the name of a position, such as "d3"
+function posName(row, col) {
+  return String.fromCharCode(97 + col) + (row + 1);
+}
+
+// Get the name of a color to show
+function title(color) {
+  return color === "blue" ? "Blue" : "Red";
+}
+
+function showError(err) {
+  elements.error.textContent = err ? err.message || String(err) : "";
+}
+
+// Draw the board, the scores and the status of the game
+function render() {
+  if (!game) {
+    return;
+  }
+  const size = game.size;
+  const valid = new Set(game.valid_moves);
+  const humanTurn = !watching && !game.over && game.turn === human && !busy;
+  const last = history.length > 0 ? history[history.length - 1].move : "";
+
+  elements.board.style.gridTemplateColumns = `0.6fr repeat(${size}, 1fr)`;
+  elements.board.style.gridTemplateRows = `0.6fr repeat(${size}, 1fr)`;
+  elements.board.replaceChildren();
+  elements.board.append(label(""));
+  for (let col = 0; col < size; col++) {
+    elements.board.append(label(String.fromCharCode(97 + col)));
+  }
+  for (let row = 0; row < size; row++) {
+    elements.board.append(label(String(row + 1)));
+    for (let col = 0; col < size; col++) {
+      elements.board.append(cell(row, col, humanTurn && valid.has(posName(row, col)), last));
+    }
+  }
+
+  elements.blueScore.textContent = game.blue_score;
+  elements.redScore.textContent = game.red_score;
+  if (!watching) {
+    elements.blueName.textContent = human === "blue" ? "You" : "Computer";
+    elements.redName.textContent = human === "red" ? "You" : "Computer";
+  }
+  document.querySelector(".score.blue").classList.toggle("turn", game.turn === "blue");
+  document.querySelector(".score.red").classList.toggle("turn", game.turn === "red");
+
+  elements.status.textContent = statusText();
+  elements.undo.disabled = busy || !history.some((move) => move.color === human);
+  elements.pass.hidden = !humanTurn || game.valid_moves[0] !== "pass";
+  if (!searching) {
+    elements.thinking.textContent = "";
+  }
+}
+
+// Get a label of a column or a row
+function label(text) {
+  const element = document.createElement("div");
+  element.className = "label";
+  element.textContent = text;
+  return element;
+}
+
+// Get a position of the board, which plays it when clicked if it's valid
+function cell(row, col, valid, last) {
+  const name = posName(row, col);
+  const element = document.createElement("button");
+  element.type = "button";
+  element.className = "cell";
+  element.setAttribute("aria-label", name);
+  element.tabIndex = valid ? 0 : -1;
+
+  const chip = game.board[row][col];
+  if (chip !== "-") {
+    const disc = document.createElement("span");
+    disc.className = "disc " + (chip === "X" ? "blue" : "red");
+    element.append(disc);
+  }
+  if (valid) {
+    element.classList.add("valid", game.turn + "-turn");
+    element.addEventListener("click", () => play(name));
+  }
+  if (name === last) {
+    element.classList.add("last");
+  }
+  if (name === considered && searching) {
+    element.classList.add("considered");
+  }
+  return element;
+}
+
+// Get the status line of the game
+function statusText() {
+  if (game.over) {
+    const score = `${game.blue_score}–${game.red_score}`;
+    if (game.winner === "tie") {
+      return `It's a tie, ${score}.`;
+    }
+    if (watching) {
+      return `${title(game.winner)} wins, ${score}.`;
+    }
+    return (game.winner === human ? "You win" : "The computer wins") + `, ${score}.`;
+  }
+  if (watching) {
+    return `${title(game.turn)} to move.`;
+  }
+  if (game.turn !== human) {
+    return "The computer is thinking…";
+  }
+  if (game.valid_moves[0] === "pass") {
+    return "You have no valid move, so you have to pass.";
+  }
+  return "Your move.";
+}
+
+// Get the moves played and draw the game again
+async function refresh() {
+  history = (await request("GET", `/api/games/${game.id}/history`)).moves;
+  elements.moves.replaceChildren();
+  for (const move of history) {
+    const item = document.createElement("li");
+    item.className = move.color;
+    item.textContent = move.move;
+    elements.moves.append(item);
+  }
+  elements.moves.scrollTop = elements.moves.scrollHeight;
+  render();
+}
+
+// Take the state of the game answered by the API, and let the computer move if it's its turn
+async function update(state) {
+  game = state;
+  await refresh();
+  if (!watching && !game.over && game.turn !== human) {
+    await computerMove();
+  }
+}
+
+// Run a request that changes the game, without taking clicks until it's answered
+async function act(action) {
+  if (busy) {
+    return;
+  }
+  busy = true;
+  showError(null);
+  render();
+  try {
+    const state = await action();
+    busy = false;
+    await update(state);
+  } catch (err) {
+    busy = false;
+    showError(err);
+    render();
+  }
+}
+
+// Play a move of the person
+function play(move) {
+  act(() => request("POST", `/api/games/${game.id}/moves`, { move: move }));
+}
+
+// Play the computer's move
+async function computerMove() {
+  busy = true;
+  render();
+  try {
+    const body = moveTime ? { movetime: moveTime } : {};
+    const answer = await request("POST", `/api/games/${game.id}/ai`, body);
+    busy = false;
+    searching = false;
+    await update(answer.state);
+  } catch (err) {
+    busy = false;
+    searching = false;
+    showError(err);
+    render();
+  }
+}
+
+// Follow the events of the game, to show how the computer's search is going, or every move when watching
+function connect(id) {
+  if (socket) {
+    socket.onclose = null;
+    socket.close();
+  }
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  socket = new WebSocket(`${scheme}//${location.host}/api/games/${id}/ws`);
+  socket.onmessage = (message) => {
+    const event = JSON.parse(message.data);
+    // The computer's answer may come before the last events of its search
+    if (event.type === "thinking" && (watching || busy)) {
+      const t = event.thinking;
+      searching = true;
+      considered = t.best;
+      const work = t.depth > 0 ? `depth ${t.depth}, ${t.nodes.toLocaleString()} positions` : `${t.playouts.toLocaleString()} playouts`;
+      elements.thinking.textContent = t.best ? `${title(event.color)} considers ${t.best}: ${work} in ${t.seconds.toFixed(1)}s` : `${title(event.color)} is thinking…`;
+      render();
+    } else if (watching && event.state) {
+      searching = false;
+      game = event.state;
+      refresh().catch(showError);
+    }
+  };
+  socket.onclose = () => {
+    if (watching) {
+      elements.thinking.textContent = "The broadcast ended.";
+    }
+  };
+}
+
+// Start a new game with the settings of the form, removing the last one
+async function newGame() {
+  const settings = new FormData(elements.form);
+  human = settings.get("color");
+  moveTime = settings.get("movetime");
+  const body = { size: Number(settings.get("size")) };
+  if (settings.get("engine")) {
+    body.engine = settings.get("engine");
+  }
+
+  if (game) {
+    request("DELETE", `/api/games/${game.id}`).catch(() => {});
+  }
+  searching = false;
+  const state = await request("POST", "/api/games", body);
+  game = state;
+  connect(state.id);
+  await update(state);
+}
+
+// Watch the game with the given id
+async function watch(id) {
+  watching = true;
+  document.body.classList.add("watching");
+  game = await request("GET", `/api/games/${id}`);
+  elements.blueName.textContent = "Blue";
+  elements.redName.textContent = "Red";
+  connect(id);
+  await refresh();
+}
+
+elements.form.addEventListener("submit", (event) => {
+  event.preventDefault();
+  if (!busy) {
+    showError(null);
+    newGame().catch(showError);
+  }
+});
+
+elements.undo.addEventListener("click", () => {
+  act(() => request("POST", `/api/games/${game.id}/undo`, { color: human }));
+});
+
+elements.pass.addEventListener("click", () => play("pass"));
+
+const watched = location.hash.match(/^#watch=([0-9a-f]+)$/);
+if (watched) {
+  watch(watched[1]).catch(showError);
+} else {
+  newGame().catch(showError);
+}
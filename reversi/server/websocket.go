@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Appended to the key of a WebSocket handshake before hashing it, as RFC 6455 says
+const webSocketGUID string = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of WebSocket frames
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xA
+)
+
+// Status codes of WebSocket close frames
+const (
+	closeGoingAway     int = 1001
+	closeProtocolError int = 1002
+	closeUnsupported   int = 1003
+	closeTooBig        int = 1009
+)
+
+// Largest message read from a client, in bytes
+const maxMessageSize int = 1 << 16
+
+// Time a write to a client may take before the client is dropped
+const writeTimeout time.Duration = 10 * time.Second
+
+// Returned by readMessage once the client closed the connection
+var errClosed = errors.New("websocket closed")
+
+// A WebSocket connection with a client, as described by RFC 6455. Messages can be written by several
+// goroutines at the same time, but only one goroutine reads them.
+type webSocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// Held while writing a frame
+	writeMu sync.Mutex
+}
+
+// An error of the client that closes the connection with the given status code
+type closeError struct {
+	code   int
+	reason string
+}
+
+func (e *closeError) Error() string {
+	return e.reason
+}
+
+// Answer a WebSocket handshake request and take over its connection, if it comes from a page of the server
+// or of one of the given origins. If the request is not a valid handshake, an error is answered and returned.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*webSocket, error) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return nil, fmt.Errorf("method not allowed")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		err := statusErrorf(http.StatusBadRequest, "expected a WebSocket handshake")
+		writeError(w, err)
+		return nil, err
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		err := statusErrorf(http.StatusUpgradeRequired, "unsupported WebSocket version, expected 13")
+		writeError(w, err)
+		return nil, err
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		err := statusErrorf(http.StatusBadRequest, "invalid Sec-WebSocket-Key %q", key)
+		writeError(w, err)
+		return nil, err
+	}
+	if !originAllowed(r, allowedOrigins) {
+		err := statusErrorf(http.StatusForbidden, "WebSockets from %v are not allowed", r.Header.Get("Origin"))
+		writeError(w, err)
+		return nil, err
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		err := statusErrorf(http.StatusInternalServerError, "the connection can't be upgraded to a WebSocket")
+		writeError(w, err)
+		return nil, err
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &webSocket{conn: conn, reader: rw.Reader}, nil
+}
+
+// Return whether the page a WebSocket handshake comes from may open it: a page of the same host as the
+// request, a page of one of the given origins, or no page at all for clients that aren't browsers and don't
+// send an Origin. Browsers always send it, so that other sites can't use the games of their visitors.
+func originAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// Return whether a header lists the given token, ignoring case, such as "Connection: keep-alive, Upgrade"
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read the next text message from the client, answering pings and putting fragmented messages together.
+// errClosed is returned once the client closes the connection, and a *closeError for a client that breaks
+// the protocol.
+func (ws *webSocket) readMessage() ([]byte, error) {
+	var message []byte
+	var opcode byte
+	for {
+		fin, frameOpcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch frameOpcode {
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// Answer with the same status code, if there is one
+			if len(payload) >= 2 {
+				payload = payload[:2]
+			}
+			ws.writeFrame(opClose, payload)
+			return nil, errClosed
+		case opContinuation:
+			if message == nil {
+				return nil, &closeError{closeProtocolError, "continuation frame without a message"}
+			}
+		case opText, opBinary:
+			if message != nil {
+				return nil, &closeError{closeProtocolError, "new message before the end of the last one"}
+			}
+			opcode = frameOpcode
+			message = []byte{}
+		default:
+			return nil, &closeError{closeProtocolError, fmt.Sprintf("unknown opcode %v", frameOpcode)}
+		}
+
+		if len(message)+len(payload) > maxMessageSize {
+			return nil, &closeError{closeTooBig, "message too big"}
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if opcode != opText {
+				return nil, &closeError{closeUnsupported, "expected text messages"}
+			}
+			return message, nil
+		}
+	}
+}
+
+// Read a frame from the client: whether it ends its message, its opcode and its unmasked payload
+func (ws *webSocket) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &closeError{closeProtocolError, "reserved bits set without an extension"}
+	}
+	// Clients must mask every frame
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, &closeError{closeProtocolError, "unmasked frame from the client"}
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	// Control frames are never fragmented and carry at most 125 bytes
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, &closeError{closeProtocolError, "invalid control frame"}
+	}
+	if length > uint64(maxMessageSize) {
+		return false, 0, nil, &closeError{closeTooBig, "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// Write a single frame with the given opcode and payload. Frames of the server are not masked.
+func (ws *webSocket) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// Write a value as a JSON text message
+func (ws *webSocket) writeJSON(value interface{}) error {
+	message, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(opText, message)
+}
+
+// Send a close frame with the given status code and reason, and close the connection
+func (ws *webSocket) close(code int, reason string) {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	ws.writeFrame(opClose, append(payload, reason...))
+	ws.conn.Close()
+}
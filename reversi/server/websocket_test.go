@@ -0,0 +1,388 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Key of the handshake of RFC 6455, and the answer it gets
+const (
+	testKey    string = "dGhlIHNhbXBsZSBub25jZQ=="
+	testAccept string = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+// A WebSocket client of a test server
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Send a WebSocket handshake to the given path of a test server, with the given headers changed from those of
+// a valid handshake, and an empty value removing a header. Get the answer, and the client if the handshake
+// succeeded, closed at the end of the test.
+func dial(t *testing.T, ts *httptest.Server, path string, headers map[string]string) (*http.Response, *testClient) {
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", testKey)
+	for name, value := range headers {
+		if name == "Method" {
+			req.Method = value
+		} else if value == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return resp, nil
+	}
+	return resp, &testClient{t: t, conn: conn, reader: reader}
+}
+
+// Connect a WebSocket client to the given game of a test server
+func connect(t *testing.T, ts *httptest.Server, id string) *testClient {
+	resp, c := dial(t, ts, "/api/games/"+id+"/ws", nil)
+	if c == nil {
+		t.Fatalf("handshake: status %v", resp.Status)
+	}
+	return c
+}
+
+// Write a frame, masked as clients have to unless mask is false
+func (c *testClient) writeFrame(fin bool, opcode byte, payload []byte, mask bool) {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0}
+	switch {
+	case len(payload) < 126:
+		frame[1] = byte(len(payload))
+	case len(payload) <= 0xFFFF:
+		frame[1] = 126
+		frame = append(frame, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame[1] = 127
+		frame = append(frame, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	if mask {
+		frame[1] |= 0x80
+		key := []byte{0x37, 0xFA, 0x21, 0x3D}
+		frame = append(frame, key...)
+		for i, b := range payload {
+			frame = append(frame, b^key[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// Read a frame of the server, which are never masked or fragmented, and get its opcode and payload
+func (c *testClient) readFrame() (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		c.t.Fatalf("frame header %x, want a final unmasked frame", header)
+	}
+	length := uint64(header[1])
+	switch length {
+	case 126:
+		var extended [2]byte
+		io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// Read the next event, skipping the progress of the computer's search
+func (c *testClient) readEvent() Event {
+	for {
+		opcode, payload := c.readFrame()
+		if opcode != opText {
+			c.t.Fatalf("frame with opcode %v and payload %q, want an event", opcode, payload)
+		}
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			c.t.Fatalf("invalid event %q: %v", payload, err)
+		}
+		if event.Type != "thinking" {
+			return event
+		}
+	}
+}
+
+// Read a close frame, failing the test if its status code isn't the given one
+func (c *testClient) readClose(code int) {
+	opcode, payload := c.readFrame()
+	if opcode != opClose || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Fatalf("frame with opcode %v and payload %q, want a close frame with the status %v", opcode, payload, code)
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	_, ts := newTestServer(t, Settings{AllowedOrigins: []string{"https://example.com"}})
+	game := "/api/games/" + createGame(t, ts, "").ID + "/ws"
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{"valid handshake", game, nil, http.StatusSwitchingProtocols},
+		{"several tokens", game, map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "WebSocket"}, http.StatusSwitchingProtocols},
+		{"page of the server", game, map[string]string{"Origin": ts.URL}, http.StatusSwitchingProtocols},
+		{"page of an allowed origin", game, map[string]string{"Origin": "https://EXAMPLE.com"}, http.StatusSwitchingProtocols},
+		{"page of another site", game, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"page of another port", game, map[string]string{"Origin": "http://127.0.0.1:1"}, http.StatusForbidden},
+		{"opaque origin", game, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"not an upgrade", game, map[string]string{"Upgrade": ""}, http.StatusBadRequest},
+		{"not a connection upgrade", game, map[string]string{"Connection": "keep-alive"}, http.StatusBadRequest},
+		{"missing key", game, map[string]string{"Sec-WebSocket-Key": ""}, http.StatusBadRequest},
+		{"short key", game, map[string]string{"Sec-WebSocket-Key": "c2hvcnQ="}, http.StatusBadRequest},
+		{"key that isn't base64", game, map[string]string{"Sec-WebSocket-Key": "not base64 at all!!!!!!!"}, http.StatusBadRequest},
+		{"old version", game, map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		{"missing version", game, map[string]string{"Sec-WebSocket-Version": ""}, http.StatusUpgradeRequired},
+		{"POST", game, map[string]string{"Method": http.MethodPost}, http.StatusMethodNotAllowed},
+		{"game that doesn't exist", "/api/games/nothing/ws", nil, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		resp, _ := dial(t, ts, test.path, test.headers)
+		if resp.StatusCode != test.status {
+			t.Errorf("%v: status %v, want %v", test.name, resp.StatusCode, test.status)
+			continue
+		}
+		switch resp.StatusCode {
+		case http.StatusSwitchingProtocols:
+			if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != testAccept || !headerHasToken(resp.Header, "Upgrade", "websocket") {
+				t.Errorf("%v: answer headers %v, want the accept key %v", test.name, resp.Header, testAccept)
+			}
+		case http.StatusUpgradeRequired:
+			if version := resp.Header.Get("Sec-WebSocket-Version"); version != "13" {
+				t.Errorf("%v: Sec-WebSocket-Version %q, want 13", test.name, version)
+			}
+		}
+	}
+
+	// Any page is allowed with *
+	_, ts = newTestServer(t, Settings{AllowedOrigins: []string{"*"}})
+	game = "/api/games/" + createGame(t, ts, "").ID + "/ws"
+	if resp, _ := dial(t, ts, game, map[string]string{"Origin": "https://evil.example"}); resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("page of another site with every origin allowed: status %v", resp.StatusCode)
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	_, ts := newTestServer(t, Settings{})
+	created := createGame(t, ts, "")
+	c := connect(t, ts, created.ID)
+	if event := c.readEvent(); event.Type != "state" || event.State == nil || event.State.Position != created.Position {
+		t.Fatalf("first event = %+v, want the state of the game", event)
+	}
+
+	// A masked move
+	c.writeFrame(true, opText, []byte(`{"type": "move", "move": "d3"}`), true)
+	if event := c.readEvent(); event.Type != "move" || event.Color != "blue" || event.Move != "d3" || strings.Join(event.Flipped, " ") != "d4" {
+		t.Errorf("event of d3 = %+v", event)
+	}
+
+	// A ping between the fragments of a message is answered with its payload right away
+	c.writeFrame(false, opText, []byte(`{"type": "move",`), true)
+	c.writeFrame(true, opPing, []byte("are you there"), true)
+	if opcode, payload := c.readFrame(); opcode != opPong || string(payload) != "are you there" {
+		t.Errorf("answer to a ping = opcode %v with %q, want a pong with its payload", opcode, payload)
+	}
+	c.writeFrame(false, opContinuation, []byte(` "move": `), true)
+	c.writeFrame(true, opContinuation, []byte(`"c5"}`), true)
+	if event := c.readEvent(); event.Type != "move" || event.Color != "red" || event.Move != "c5" {
+		t.Errorf("event of the fragmented c5 = %+v", event)
+	}
+
+	// Messages that fail are answered with an error, and the connection stays open
+	for _, message := range []string{`{"type": "move", "move": "a1"}`, `{"type": "dance"}`, `not json`} {
+		c.writeFrame(true, opText, []byte(message), true)
+		if event := c.readEvent(); event.Type != "error" || event.Error == "" {
+			t.Errorf("answer to %v = %+v, want an error", message, event)
+		}
+	}
+
+	// The computer's move, asked for over the WebSocket
+	c.writeFrame(true, opText, []byte(`{"type": "ai", "movetime": "1s"}`), true)
+	if event := c.readEvent(); event.Type != "move" || event.Color != "blue" || event.State.Moves != 3 {
+		t.Errorf("event of the AI move = %+v", event)
+	}
+
+	// A close is answered with the same status code
+	closing := []byte{0x03, 0xE8}
+	c.writeFrame(true, opClose, append(closing, "bye"...), true)
+	if opcode, payload := c.readFrame(); opcode != opClose || string(payload) != string(closing) {
+		t.Errorf("answer to a close = opcode %v with %q, want a close with the status 1000", opcode, payload)
+	}
+}
+
+func TestWebSocketBrokenClients(t *testing.T) {
+	_, ts := newTestServer(t, Settings{})
+	id := createGame(t, ts, "").ID
+
+	tests := []struct {
+		name  string
+		write func(c *testClient)
+		code  int
+	}{
+		{"unmasked frame", func(c *testClient) {
+			c.writeFrame(true, opText, []byte(`{"type": "move", "move": "d3"}`), false)
+		}, closeProtocolError},
+		{"frame bigger than a message", func(c *testClient) {
+			// Only the header is sent, the frame is refused from its length
+			c.conn.Write([]byte{0x81, 0xFF, 0, 0, 0, 0, 0, 0x01, 0, 0x01})
+		}, closeTooBig},
+		{"fragments bigger than a message", func(c *testClient) {
+			fragment := make([]byte, maxMessageSize/2+1)
+			c.writeFrame(false, opText, fragment, true)
+			c.writeFrame(true, opContinuation, fragment, true)
+		}, closeTooBig},
+		{"continuation without a message", func(c *testClient) {
+			c.writeFrame(true, opContinuation, []byte("{}"), true)
+		}, closeProtocolError},
+		{"new message before the end of the last one", func(c *testClient) {
+			c.writeFrame(false, opText, []byte("{"), true)
+			c.writeFrame(true, opText, []byte("{}"), true)
+		}, closeProtocolError},
+		{"fragmented ping", func(c *testClient) {
+			c.writeFrame(false, opPing, []byte("ping"), true)
+		}, closeProtocolError},
+		{"binary message", func(c *testClient) {
+			c.writeFrame(true, opBinary, []byte{1, 2, 3}, true)
+		}, closeUnsupported},
+		{"unknown opcode", func(c *testClient) {
+			c.writeFrame(true, 0x3, nil, true)
+		}, closeProtocolError},
+		{"reserved bits", func(c *testClient) {
+			c.conn.Write([]byte{0xC1, 0x80, 0, 0, 0, 0})
+		}, closeProtocolError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := connect(t, ts, id)
+			c.readEvent()
+			test.write(c)
+			c.readClose(test.code)
+			if _, err := c.reader.ReadByte(); err != io.EOF {
+				t.Errorf("the connection is still open after the close: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebSocketSpectator(t *testing.T) {
+	// A game of random moves on a small board where a color has to pass before the end
+	rng := rand.New(rand.NewSource(1))
+	var moves []engine.Move
+	for !hasPass(moves) {
+		r, _ := engine.NewSize(6, engine.Blue)
+		for !r.IsOver() {
+			if valid := r.ValidPositions(); len(valid) > 0 {
+				r.Play(valid[rng.Intn(len(valid))])
+			} else {
+				r.Pass()
+			}
+		}
+		moves = r.PlayedMoves()
+	}
+
+	// The spectator watches the game played through the HTTP API, move by move, until it is over
+	_, ts := newTestServer(t, Settings{})
+	created := createGame(t, ts, `{"size": 6}`)
+	c := connect(t, ts, created.ID)
+	c.readEvent()
+	r, _ := engine.NewSize(6, engine.Blue)
+	for _, move := range moves {
+		name := moveName(r, move.Pos)
+		replay(r, move)
+		if status := request(t, ts, http.MethodPost, "/api/games/"+created.ID+"/moves", `{"move": "`+name+`"}`, nil); status != http.StatusOK {
+			t.Fatalf("playing %v: status %v", name, status)
+		}
+
+		event := c.readEvent()
+		wantType := "move"
+		if move.Pos == engine.NoMove {
+			wantType = "pass"
+		}
+		if event.Type != wantType || event.Color != colorName(move.Color) || event.State == nil || event.State.Position != r.Position() {
+			t.Fatalf("event of %v = %+v, want a %v of %v", name, event, wantType, colorName(move.Color))
+		}
+		if wantType == "move" && (event.Move != name || len(event.Flipped) != move.Flipped.Count()) {
+			t.Errorf("event of %v = %+v, want its %v flipped chips", name, event, move.Flipped.Count())
+		}
+	}
+
+	event := c.readEvent()
+	if event.Type != "over" || event.State == nil || !event.State.Over || event.State.Winner == "" {
+		t.Errorf("event at the end of the game = %+v, want over with the winner", event)
+	}
+
+	// Removing the game disconnects the spectator
+	request(t, ts, http.MethodDelete, "/api/games/"+created.ID, "", nil)
+	c.readClose(closeGoingAway)
+}
+
+// Return whether a color passed in the given moves
+func hasPass(moves []engine.Move) bool {
+	for _, move := range moves {
+		if move.Pos == engine.NoMove {
+			return true
+		}
+	}
+	return false
+}
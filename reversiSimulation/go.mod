@@ -0,0 +1,8 @@
+module github.com/M-Balghonaim/Reversi-AI/reversiSimulation
+
+go 1.16
+
+require github.com/M-Balghonaim/Reversi-AI/reversi v0.0.0
+
+// The engine is the reversi module next to this one
+replace github.com/M-Balghonaim/Reversi-AI/reversi => ../reversi
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// Number of games each pair of a league plays when no number is given
+const DefaultLeagueGames int = 10
+
+// Settings every game of a league is played with. Ratings are only comparable between games played with
+// the same settings, so a ladder keeps them.
+type LeagueSettings struct {
+	Size           int    `json:"size"`
+	MoveTime       string `json:"movetime"`
+	Clock          string `json:"clock"`
+	Increment      string `json:"increment"`
+	EndgameEmpties int    `json:"endgame"`
+	Playouts       int    `json:"playouts"`
+	Book           string `json:"book"`
+}
+
+// Get the league settings of the given game settings
+func leagueSettings(options Options) LeagueSettings {
+	return LeagueSettings{
+		Size:           options.Size,
+		MoveTime:       options.TimeControl.MoveTime.String(),
+		Clock:          options.TimeControl.Clock.String(),
+		Increment:      options.TimeControl.Increment.String(),
+		EndgameEmpties: options.EndgameEmpties,
+		Playouts:       options.Playouts,
+		Book:           options.Book,
+	}
+}
+
+// The players of a league and the results of every pair of them, as saved between runs
+type Ladder struct {
+	Settings LeagueSettings `json:"settings"`
+	// Player specs, in the order they joined the ladder
+	Players []string `json:"players"`
+	// Results of each pair of players that played each other
+	Pairs []Standings `json:"pairs"`
+}
+
+// Read the ladder saved in the given file, or get an empty ladder if there is no such file
+func LoadLadder(file string) (*Ladder, error) {
+	contents, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return new(Ladder), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ladder := new(Ladder)
+	if err := json.Unmarshal(contents, ladder); err != nil {
+		return nil, fmt.Errorf("invalid ladder %v: %v", file, err)
+	}
+	return ladder, nil
+}
+
+// Save the ladder to the given file
+func (l *Ladder) Save(file string) error {
+	contents, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(file, append(contents, '\n'))
+}
+
+// Add the given player specs to the ladder, skipping the ones already in it
+func (l *Ladder) AddPlayers(specs []string) error {
+	for _, spec := range specs {
+		if _, err := engine.NewPlayer(spec, engine.PlayerConfig{}); err != nil {
+			return err
+		}
+		if l.hasPlayer(spec) {
+			continue
+		}
+		l.Players = append(l.Players, spec)
+	}
+	return nil
+}
+
+// Return whether the given player spec is in the ladder
+func (l *Ladder) hasPlayer(spec string) bool {
+	for _, player := range l.Players {
+		if player == spec {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the results of the given pair of players, adding them to the ladder if they haven't played yet
+func (l *Ladder) pair(player, opponent string) *Standings {
+	for i := range l.Pairs {
+		s := &l.Pairs[i]
+		if (s.Player == player && s.Opponent == opponent) || (s.Player == opponent && s.Opponent == player) {
+			return s
+		}
+	}
+	l.Pairs = append(l.Pairs, Standings{Player: player, Opponent: opponent})
+	return &l.Pairs[len(l.Pairs)-1]
+}
+
+// Add the results of a tournament to the results of the pair that played it
+func (s *Standings) merge(results Standings) {
+	if results.Player == s.Player {
+		s.Wins += results.Wins
+		s.Losses += results.Losses
+	} else {
+		s.Wins += results.Losses
+		s.Losses += results.Wins
+	}
+	s.Ties += results.Ties
+}
+
+// Play the games the ladder is missing, so that every pair of players has played the number of games of
+// the options, and call pairDone with the results of each pair after its games. Pairs that already played
+// enough games are not played again, so adding a player to a ladder only plays the games of the new player.
+func RunLeague(options TournamentOptions, ladder *Ladder, records recordWriter, pairDone func(Standings) error) error {
+	settings := leagueSettings(options.Options)
+	if len(ladder.Pairs) > 0 && ladder.Settings != settings {
+		return fmt.Errorf("the ladder was played with different settings: %+v, not %+v", ladder.Settings, settings)
+	}
+	ladder.Settings = settings
+
+	if len(ladder.Players) < 2 {
+		return fmt.Errorf("a league needs at least two players, the ladder has %v", len(ladder.Players))
+	}
+
+	for i, player := range ladder.Players {
+		for _, opponent := range ladder.Players[i+1:] {
+			results := ladder.pair(player, opponent)
+			if results.Games() >= options.Games {
+				continue
+			}
+
+			// Keep swapping colors from where the pair stopped
+			pairOptions := options
+			pairOptions.Red, pairOptions.Blue = results.Player, results.Opponent
+			if results.Games()%2 == 1 {
+				pairOptions.Red, pairOptions.Blue = pairOptions.Blue, pairOptions.Red
+			}
+			pairOptions.Games = options.Games - results.Games()
+			// The games of a pair that is played again must not repeat the ones it already played
+			pairOptions.Seed = engine.DeriveSeed(options.Seed, int64(results.Games()))
+
+			standings, err := RunTournament(pairOptions, records)
+			if err != nil {
+				return err
+			}
+			results.merge(standings)
+
+			if err := pairDone(*results); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// The rating of a player of a ladder
+type Rating struct {
+	Player string
+	// Elo rating, where the average rating of the players is 0
+	Elo float64
+	// Half the width of the 95% confidence interval of the Elo rating
+	Error float64
+	Games int
+	// Points won, where a win is worth one point and a tie half a point
+	Points float64
+}
+
+// Get the Elo ratings of the players who played, from the best to the worst.
+// The ratings are the maximum likelihood estimate of the Bradley-Terry model, in which player i beats
+// player j with probability gamma[i] / (gamma[i] + gamma[j]). They are found with the MM algorithm of
+// Hunter (2004). Every pair gets one virtual tie, so that a player who won or lost every game still
+// has a finite rating.
+func (l *Ladder) Ratings() []Rating {
+	index := make(map[string]int)
+	for i, player := range l.Players {
+		index[player] = i
+	}
+
+	n := len(l.Players)
+	ratings := make([]Rating, n)
+	games := make([][]float64, n)
+	points := make([]float64, n)
+	for i, player := range l.Players {
+		ratings[i].Player = player
+		games[i] = make([]float64, n)
+	}
+
+	for _, pair := range l.Pairs {
+		i, iOk := index[pair.Player]
+		j, jOk := index[pair.Opponent]
+		if !iOk || !jOk || pair.Games() == 0 {
+			continue
+		}
+
+		ratings[i].Games += pair.Games()
+		ratings[j].Games += pair.Games()
+		ratings[i].Points += float64(pair.Wins) + 0.5*float64(pair.Ties)
+		ratings[j].Points += float64(pair.Losses) + 0.5*float64(pair.Ties)
+
+		games[i][j] += float64(pair.Games() + 1)
+		games[j][i] += float64(pair.Games() + 1)
+		points[i] += float64(pair.Wins) + 0.5*float64(pair.Ties) + 0.5
+		points[j] += float64(pair.Losses) + 0.5*float64(pair.Ties) + 0.5
+	}
+
+	gamma := make([]float64, n)
+	for i := range gamma {
+		gamma[i] = 1
+	}
+
+	next := make([]float64, n)
+	for iteration := 0; iteration < 10000; iteration++ {
+		change := 0.0
+		for i := range gamma {
+			next[i] = gamma[i]
+
+			expected := 0.0
+			for j := range gamma {
+				if games[i][j] > 0 {
+					expected += games[i][j] / (gamma[i] + gamma[j])
+				}
+			}
+			if expected > 0 {
+				next[i] = points[i] / expected
+			}
+			change = math.Max(change, math.Abs(math.Log(next[i]/gamma[i])))
+		}
+		copy(gamma, next)
+
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	// Elo ratings are 400 times the base 10 logarithm of gamma, centered on the players who played
+	const eloScale = 400 / math.Ln10
+	rated := make([]Rating, 0, n)
+	mean := 0.0
+	for i := range ratings {
+		if ratings[i].Games == 0 {
+			continue
+		}
+
+		// The standard error comes from the Fisher information of the player's log gamma
+		information := 0.0
+		for j := range gamma {
+			p := gamma[i] / (gamma[i] + gamma[j])
+			information += games[i][j] * p * (1 - p)
+		}
+
+		ratings[i].Elo = eloScale * math.Log(gamma[i])
+		ratings[i].Error = 1.96 * eloScale / math.Sqrt(information)
+		mean += ratings[i].Elo
+		rated = append(rated, ratings[i])
+	}
+
+	for i := range rated {
+		rated[i].Elo -= mean / float64(len(rated))
+	}
+	sort.SliceStable(rated, func(i, j int) bool {
+		return rated[i].Elo > rated[j].Elo
+	})
+
+	return rated
+}
+
+// Write the ratings of the players as a table, such as
+// Rank  Player                Elo  ±95%  Games  Score
+// 1     mcts                  +85   42     20  62.5%
+func writeRatings(w io.Writer, ratings []Rating) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Rank\tPlayer\tElo\t±95%\tGames\tScore")
+	for i, rating := range ratings {
+		fmt.Fprintf(table, "%v\t%v\t%+.0f\t%.0f\t%v\t%.1f%%\n",
+			i+1, rating.Player, rating.Elo, rating.Error, rating.Games, 100*rating.Points/float64(rating.Games))
+	}
+	return table.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRatingsTwoPlayers(t *testing.T) {
+	ladder := &Ladder{
+		Players: []string{"weak", "strong"},
+		Pairs:   []Standings{{Player: "strong", Opponent: "weak", Wins: 30, Losses: 10}},
+	}
+	ratings := ladder.Ratings()
+	if len(ratings) != 2 || ratings[0].Player != "strong" || ratings[1].Player != "weak" {
+		t.Fatalf("ratings = %+v, want strong then weak", ratings)
+	}
+
+	// With the virtual tie, strong scored 30.5 of 41 points, so its gamma is 30.5/10.5 times weak's
+	difference := 400 * math.Log10(30.5/10.5)
+	if math.Abs(ratings[0].Elo-difference/2) > 1e-6 || math.Abs(ratings[1].Elo+difference/2) > 1e-6 {
+		t.Errorf("Elo ratings = %.6f and %.6f, want %.6f and %.6f", ratings[0].Elo, ratings[1].Elo, difference/2, -difference/2)
+	}
+	if ratings[0].Games != 40 || ratings[0].Points != 30 || ratings[1].Points != 10 {
+		t.Errorf("ratings = %+v, want 40 games each with 30 and 10 points", ratings)
+	}
+}
+
+func TestRatingsThreePlayers(t *testing.T) {
+	// With the virtual ties, a scores twice the points of b, b twice those of c, and a four times those of
+	// c, which the ratings of gammas 4, 2 and 1 fit exactly
+	ladder := &Ladder{
+		Players: []string{"c", "a", "b", "idle"},
+		Pairs: []Standings{
+			{Player: "a", Opponent: "b", Wins: 5, Losses: 2, Ties: 1},
+			{Player: "c", Opponent: "b", Wins: 2, Losses: 5, Ties: 1},
+			{Player: "a", Opponent: "c", Wins: 7, Losses: 1, Ties: 1},
+		},
+	}
+	ratings := ladder.Ratings()
+
+	step := 400 * math.Log10(2)
+	want := []struct {
+		player string
+		elo    float64
+	}{{"a", step}, {"b", 0}, {"c", -step}}
+	if len(ratings) != len(want) {
+		t.Fatalf("ratings = %+v, want the 3 players who played", ratings)
+	}
+	for i, rating := range ratings {
+		if rating.Player != want[i].player || math.Abs(rating.Elo-want[i].elo) > 1e-6 {
+			t.Errorf("rating %v = %v %.6f, want %v %.6f", i+1, rating.Player, rating.Elo, want[i].player, want[i].elo)
+		}
+		if rating.Error <= 0 || math.IsInf(rating.Error, 0) {
+			t.Errorf("error of the rating of %v = %v", rating.Player, rating.Error)
+		}
+	}
+}
+
+func TestRatingsUnbeaten(t *testing.T) {
+	// The virtual ties keep the ratings of a player who won every game finite
+	ladder := &Ladder{
+		Players: []string{"a", "b", "c"},
+		Pairs: []Standings{
+			{Player: "a", Opponent: "b", Wins: 10},
+			{Player: "a", Opponent: "c", Wins: 10},
+			{Player: "b", Opponent: "c", Wins: 6, Losses: 4},
+		},
+	}
+	ratings := ladder.Ratings()
+	if len(ratings) != 3 || ratings[0].Player != "a" || ratings[1].Player != "b" || ratings[2].Player != "c" {
+		t.Fatalf("ratings = %+v, want a, b then c", ratings)
+	}
+
+	sum := 0.0
+	for _, rating := range ratings {
+		if math.IsNaN(rating.Elo) || math.IsInf(rating.Elo, 0) {
+			t.Errorf("Elo rating of %v = %v", rating.Player, rating.Elo)
+		}
+		sum += rating.Elo
+	}
+	if math.Abs(sum) > 1e-6 {
+		t.Errorf("Elo ratings add up to %v, want them centered on 0", sum)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io/ioutil"
+	"os"
+)
+
+// Adds the first moves of every game to an opening book file as the games finish. Each move is weighted
+// by the points the color that played it won, so the book learns which openings do well.
+type bookLearner struct {
+	book *engine.Book
+	file string
+	// Board size of the games, and number of moves of each game to add
+	size  int
+	moves int
+}
+
+// Get a learner that adds the given number of moves of each game to the given book file, which is created
+// if needed
+func newBookLearner(file string, size, moves int) (*bookLearner, error) {
+	book := engine.NewBook()
+	f, err := os.Open(file)
+	if err == nil {
+		err = book.Read(f, size)
+		f.Close()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &bookLearner{book: book, file: file, size: size, moves: moves}, nil
+}
+
+// Add the opening of a game to the book and save it
+func (l *bookLearner) Write(record GameRecord) error {
+	r, err := engine.ParseTranscript(record.Transcript, l.size)
+	if err != nil {
+		return err
+	}
+	if err := l.book.AddGame(r, l.moves); err != nil {
+		return err
+	}
+
+	var contents bytes.Buffer
+	if err := l.book.Write(&contents); err != nil {
+		return err
+	}
+	return replaceFile(l.file, contents.Bytes())
+}
+
+// Writes the results of the games to several writers
+type multiRecordWriter []recordWriter
+
+// Write the results of a game to every writer
+func (m multiRecordWriter) Write(record GameRecord) error {
+	for _, w := range m {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get a writer of the results to both given writers, either of which may be nil
+func combineRecords(records, learner recordWriter) recordWriter {
+	if records == nil {
+		return learner
+	} else if learner == nil {
+		return records
+	}
+	return multiRecordWriter{records, learner}
+}
+
+// Replace the contents of a file at once, so that it's never left half written
+func replaceFile(file string, contents []byte) error {
+	temp := file + ".tmp"
+	if err := ioutil.WriteFile(temp, contents, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, file)
+}
@@ -1,12 +1,281 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/console"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/server"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
 func main() {
 
+	// Read the game settings
+	var options TournamentOptions
+	flag.DurationVar(&options.TimeControl.MoveTime, "movetime", engine.DefaultMoveTime, "maximum time the computer may take per move")
+	flag.DurationVar(&options.TimeControl.Clock, "clock", 0, "total time each side has for the whole game (0 for no game clock)")
+	flag.DurationVar(&options.TimeControl.Increment, "increment", 0, "time added to a side's clock after each of its moves")
+	flag.IntVar(&options.Size, "size", engine.DefaultSize, fmt.Sprintf("number of positions in a row or column of the board: an even number from %v to %v", engine.MinSize, engine.MaxSize))
+	flag.IntVar(&options.EndgameEmpties, "endgame", engine.DefaultEndgameEmpties, "number of empty positions at which the computer solves the rest of the game exactly")
+	players := strings.Join(console.PlayerNames(), ", ")
+	flag.StringVar(&options.Blue, "blue", "flat:policy=random", "player of the blue chips: "+players)
+	flag.StringVar(&options.Red, "red", "flat", "player of the red chips: "+players)
+	flag.StringVar(&options.First, "first", "red", "color that moves first: red or blue")
+	flag.IntVar(&options.Playouts, "playouts", engine.Playouts, "number of playouts for each valid position")
+	flag.StringVar(&options.Book, "book", "builtin", "opening book of the computer: builtin, none, or book files, comma-separated to combine them")
+	flag.Int64Var(&options.Seed, "seed", 0, "seed for the random numbers, the same seed and settings play the same games (0 picks one at random)")
+	flag.IntVar(&options.Games, "games", 0, "number of games of a tournament between the red and blue players, who swap colors after every game (0 to play games one after another on the terminal)")
+	flag.IntVar(&options.Parallel, "parallel", 1, "number of tournament games played at the same time")
+	flag.StringVar(&options.Format, "format", "csv", "format of the tournament results: csv or json")
+	output := flag.String("output", "", "file to write the tournament results to (standard output if not given, or discarded in a league)")
+	league := flag.String("league", "", "file of the ladder of a round-robin league between the ladder's players and the -players, which is created if needed and updated after every pair")
+	leaguePlayers := flag.String("players", "", "space-separated player specs to add to the league, such as \"mcts flat:loss=-5 alphabeta:depth=6\"")
+	var sprtOptions SPRTOptions
+	sprt := flag.Bool("sprt", false, "test whether the -candidate player gains Elo over the -baseline player with a sequential probability ratio test, playing pairs of games until it decides or -games are played (0 for no limit)")
+	flag.StringVar(&sprtOptions.Baseline, "baseline", "flat", "player the candidate is tested against: "+players)
+	flag.StringVar(&sprtOptions.Candidate, "candidate", "mcts", "player tested against the baseline: "+players)
+	flag.Float64Var(&sprtOptions.Elo0, "elo0", 0, "Elo gain of the candidate under the null hypothesis H0")
+	flag.Float64Var(&sprtOptions.Elo1, "elo1", 5, "Elo gain of the candidate under the alternative hypothesis H1")
+	flag.Float64Var(&sprtOptions.Alpha, "alpha", 0.05, "probability of accepting H1 when H0 holds")
+	flag.Float64Var(&sprtOptions.Beta, "beta", 0.05, "probability of accepting H0 when H1 holds")
+	flag.IntVar(&sprtOptions.OpeningMoves, "opening", 4, "number of random moves of the opening both games of a pair start from")
+	learn := flag.String("learn", "", "book file to add the openings of the tournament, league or SPRT games to, weighted by their results (created if needed)")
+	learnMoves := flag.Int("learnmoves", 12, "number of moves of each game added to the -learn book")
+	httpAddr := flag.String("http", "", "also serve the games played on the terminal over HTTP on the given address, such as :8080, for WebSocket clients to watch")
+	flag.Parse()
+
+	if *sprt || *league != "" || options.Games > 0 {
+		// Games played without being displayed get a seed that can be given again to replay them
+		if options.Seed == 0 {
+			options.Seed = time.Now().UnixNano()
+		}
+
+		// The book is loaded once for all the games
+		var err error
+		if options.OpeningBook, err = console.LoadBook(options.Book, options.Size); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var learner recordWriter
+	if *learn != "" {
+		bookLearner, err := newBookLearner(*learn, options.Size, *learnMoves)
+		if err != nil {
+			log.Fatal(err)
+		}
+		learner = bookLearner
+	}
+
+	if *sprt {
+		sprtOptions.TournamentOptions = options
+		os.Exit(runSPRT(sprtOptions, *output, learner))
+	}
+
+	if *league != "" {
+		runLeague(options, *league, strings.Fields(*leaguePlayers), *output, learner)
+		return
+	}
+
+	if options.Games > 0 {
+		runTournament(options, *output, learner)
+		return
+	}
+
 	// Initialize a new game
-	game := NewGame()
+	game, err := NewGame(options.Options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var broadcast *server.Broadcast
+	if *httpAddr != "" {
+		if broadcast, err = startBroadcast(*httpAddr, game, options.Options); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Play turns
 	for !game.End {
+		if broadcast != nil {
+			// The players are new in every game
+			for _, color := range []int{engine.Blue, engine.Red} {
+				engine.SetReport(game.Players[color], broadcast.Report(color))
+			}
+		}
+
 		game.PlayTurn()
+
+		if broadcast != nil {
+			broadcast.Update(game.Reversi)
+		}
+	}
+}
+
+// Serve the games played on the terminal over HTTP on the given address, starting with the given game,
+// and return the broadcast to update with their moves. The server also plays games of its own against
+// MCTS, as reversi -http does.
+func startBroadcast(addr string, game *Game, options Options) (*server.Broadcast, error) {
+	first, err := console.ParseColor(options.First)
+	if err != nil {
+		return nil, err
+	}
+	s, err := server.NewServer(server.Settings{
+		Size:     options.Size,
+		First:    first,
+		Engine:   "mcts",
+		Config:   engine.PlayerConfig{EndgameEmpties: options.EndgameEmpties, Playouts: options.Playouts, Seed: options.Seed},
+		MoveTime: options.TimeControl.MoveTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	broadcast, err := s.NewBroadcast(game.Reversi, fmt.Sprintf("%v (blue) vs %v (red)", options.Blue, options.Red))
+	if err != nil {
+		return nil, err
+	}
+
+	// Listen before playing, so that a bad address fails right away
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		log.Fatal(http.Serve(listener, s))
+	}()
+
+	fmt.Printf("Watch the games in a browser at http://%v/#watch=%v or over WebSocket at ws://%v/api/games/%v/ws\n\n",
+		listener.Addr(), broadcast.ID(), listener.Addr(), broadcast.ID())
+	return broadcast, nil
+}
+
+// Play a tournament without displaying the games, write the results to the given file and to the
+// learner, if any, and print the standings
+func runTournament(options TournamentOptions, output string, learner recordWriter) {
+	var results io.Writer = os.Stdout
+	summary := os.Stderr
+
+	// The standings go to the standard output, unless the results already do
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		results = f
+		summary = os.Stdout
+	}
+
+	records, err := newRecordWriter(results, options.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	standings, err := RunTournament(options, combineRecords(records, learner))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(summary, standings)
+	fmt.Fprintf(summary, "Replay with -seed %v\n", options.Seed)
+}
+
+// Exit codes of the SPRT: the candidate passes when H1 is accepted
+const (
+	exitAcceptH1  = 0
+	exitAcceptH0  = 2
+	exitUndecided = 3
+)
+
+// Play a sequential probability ratio test between the candidate and the baseline, write the results of
+// the games to the output file if there is one and to the learner, if any, print the LLR after every
+// pair and the verdict, and return the exit code of the verdict
+func runSPRT(options SPRTOptions, output string, learner recordWriter) int {
+	var records recordWriter
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if records, err = newRecordWriter(f, options.Format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	lower, upper := NewSPRT(options).Bounds()
+	fmt.Printf("SPRT of %v against %v: H0 Elo gain %v, H1 Elo gain %v, alpha %v, beta %v, LLR bounds (%.2f, %.2f)\n",
+		options.Candidate, options.Baseline, options.Elo0, options.Elo1, options.Alpha, options.Beta, lower, upper)
+
+	s, err := RunSPRT(options, combineRecords(records, learner), func(pair int, opening string, points float64, s *SPRT) {
+		fmt.Printf("pair %v: %v-%v from %v, %v\n", pair, points, 2-points, opening, s)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	games := 2 * s.Pairs()
+	code := exitUndecided
+	switch s.Verdict() {
+	case AcceptH1:
+		fmt.Printf("H1 accepted after %v games: %v gains at least %v Elo over %v\n", games, options.Candidate, options.Elo1, options.Baseline)
+		code = exitAcceptH1
+	case AcceptH0:
+		fmt.Printf("H0 accepted after %v games: %v gains at most %v Elo over %v\n", games, options.Candidate, options.Elo0, options.Baseline)
+		code = exitAcceptH0
+	default:
+		fmt.Printf("No verdict after %v games: the LLR is still between %.2f and %.2f\n", games, lower, upper)
+	}
+	fmt.Printf("Replay with -seed %v\n", options.Seed)
+	return code
+}
+
+// Play the missing games of the league saved in the given file with the given players added to it, write
+// the results of the games to the output file if there is one and to the learner, if any, and print the
+// ratings
+func runLeague(options TournamentOptions, file string, players []string, output string, learner recordWriter) {
+	if options.Games == 0 {
+		options.Games = DefaultLeagueGames
+	}
+
+	ladder, err := LoadLadder(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ladder.AddPlayers(players); err != nil {
+		log.Fatal(err)
+	}
+
+	var records recordWriter
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if records, err = newRecordWriter(f, options.Format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Save the ladder after every pair, so that a stopped league loses at most the games of one pair
+	err = RunLeague(options, ladder, combineRecords(records, learner), func(standings Standings) error {
+		fmt.Println(standings)
+		return ladder.Save(file)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println()
+	if err := writeRatings(os.Stdout, ladder.Ratings()); err != nil {
+		log.Fatal(err)
 	}
+	fmt.Printf("\nReplay with -seed %v\n", options.Seed)
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/console"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"math"
+	"sync"
+)
+
+// Settings of a sequential probability ratio test between a baseline and a candidate player, on top of
+// the tournament settings. The games are played in pairs from the same random opening, with the colors
+// swapped in the second game, until the test accepts one of its hypotheses or the tournament's number
+// of games is reached, if there is one.
+type SPRTOptions struct {
+	TournamentOptions
+	// Player specs of the two players
+	Baseline  string
+	Candidate string
+	// Elo gain of the candidate under the null hypothesis H0 and under the alternative hypothesis H1
+	Elo0 float64
+	Elo1 float64
+	// Probabilities of accepting H1 when H0 holds, and of accepting H0 when H1 holds
+	Alpha float64
+	Beta  float64
+	// Number of random moves played from the four starting chips to make the opening of each pair
+	OpeningMoves int
+}
+
+// Outcome of a test
+const (
+	Undecided = iota
+	AcceptH0
+	AcceptH1
+)
+
+// State of a sequential probability ratio test of the Elo gain of the candidate. Games are counted in
+// pairs, whose scores cancel out most of the advantage of the opening and of moving first. The test is
+// the generalized SPRT on the pentanomial distribution of the pair scores, with the logistic Elo model.
+type SPRT struct {
+	// Wins, losses and ties of the candidate against the baseline
+	Standings
+	elo0, elo1 float64
+	// Bounds of the log-likelihood ratio at which H0 and H1 are accepted
+	lower, upper float64
+	// Number of pairs in which the candidate scored 0, 0.5, 1, 1.5 and 2 points
+	pairs [5]int
+}
+
+// Initialize and return a test with the given hypotheses and error probabilities
+func NewSPRT(options SPRTOptions) *SPRT {
+	candidate, baseline := playerNames(options.Options)
+	return &SPRT{
+		Standings: Standings{Player: candidate, Opponent: baseline},
+		elo0:      options.Elo0,
+		elo1:      options.Elo1,
+		lower:     math.Log(options.Beta / (1 - options.Alpha)),
+		upper:     math.Log((1 - options.Beta) / options.Alpha),
+	}
+}
+
+// Get the bounds of the log-likelihood ratio at which H0 and H1 are accepted
+func (s *SPRT) Bounds() (float64, float64) {
+	return s.lower, s.upper
+}
+
+// Get the number of pairs of games played
+func (s *SPRT) Pairs() int {
+	n := 0
+	for _, count := range s.pairs {
+		n += count
+	}
+	return n
+}
+
+// Add the results of a pair of games and return the points the candidate won in them
+func (s *SPRT) add(records [2]GameRecord) float64 {
+	// Count half points, so that they index pairs
+	halfPoints := 0
+	for _, record := range records {
+		s.Standings.add(record)
+		if record.Winner == s.Player {
+			halfPoints += 2
+		} else if record.Winner != s.Opponent {
+			halfPoints += 1
+		}
+	}
+	s.pairs[halfPoints] += 1
+	return float64(halfPoints) / 2
+}
+
+// Get the expected score of a player who is the given number of Elo points stronger than the opponent
+func eloScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// Pair scores of one virtual pair between evenly matched players, which is added to the pairs played so
+// that the variance of the scores isn't 0 when every pair has the same score, and the test can't
+// decide after a handful of pairs
+var priorPair = [5]float64{1.0 / 16, 4.0 / 16, 6.0 / 16, 4.0 / 16, 1.0 / 16}
+
+// Get the log-likelihood ratio of H1 against H0, using the normal approximation of the mean pair score
+func (s *SPRT) LLR() float64 {
+	n := float64(s.Pairs())
+	if n == 0 {
+		return 0
+	}
+
+	var freq [5]float64
+	mean := 0.0
+	for i, count := range s.pairs {
+		freq[i] = (float64(count) + priorPair[i]) / (n + 1)
+		mean += freq[i] * float64(i) / 4
+	}
+	variance := 0.0
+	for i := range freq {
+		variance += freq[i] * math.Pow(float64(i)/4-mean, 2)
+	}
+
+	s0, s1 := eloScore(s.elo0), eloScore(s.elo1)
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// Get the outcome of the test so far
+func (s *SPRT) Verdict() int {
+	llr := s.LLR()
+	if llr >= s.upper {
+		return AcceptH1
+	} else if llr <= s.lower {
+		return AcceptH0
+	}
+	return Undecided
+}
+
+// Get the summary of the test so far, such as
+// "10 wins, 7 losses, 7 ties, score 56.2%, LLR 0.63 (-2.94, 2.94)"
+func (s *SPRT) String() string {
+	rate, _, _ := s.WinRate()
+	return fmt.Sprintf("%v wins, %v losses, %v ties, score %.1f%%, LLR %.2f (%.2f, %.2f)",
+		s.Wins, s.Losses, s.Ties, 100*rate, s.LLR(), s.lower, s.upper)
+}
+
+// A pair of games from the same opening, with the colors swapped
+type gamePair struct {
+	number  int
+	opening *engine.Reversi
+}
+
+// Play the pair of games and return their results
+func (p gamePair) play(options SPRTOptions) [2]GameRecord {
+	var records [2]GameRecord
+	for i := range records {
+		// The settings were checked before starting
+		t, _ := newTournamentGame(options.TournamentOptions, 2*p.number-1+i)
+		t.Reversi = p.opening.Copy()
+		records[i] = t.play(options.TimeControl)
+	}
+	return records
+}
+
+// Get the random opening of the given pair: the given number of random moves from the four starting chips
+func randomOpening(options SPRTOptions, pair int) (*engine.Reversi, error) {
+	first, err := console.ParseColor(options.First)
+	if err != nil {
+		return nil, err
+	}
+	r, err := engine.NewSize(options.Size, first)
+	if err != nil {
+		return nil, err
+	}
+
+	// Games are numbered from 1, so negative numbers give the openings their own seeds
+	player := engine.NewRandomPlayer()
+	player.Rand = engine.NewRand(engine.DeriveSeed(options.Seed, int64(-pair)))
+	for i := 0; i < options.OpeningMoves && !r.IsOver(); i++ {
+		pos, _ := player.BestMove(r, 0)
+		if pos == engine.NoMove {
+			r.Pass()
+		} else {
+			r.Play(pos)
+		}
+	}
+	return r, nil
+}
+
+// Play pairs of games between the candidate and the baseline until the test accepts a hypothesis or the
+// number of games of the options is reached, writing the result of each game to records unless records
+// is nil, and calling pairDone after each pair with its number, its opening and the candidate's points.
+// Pairs are numbered in the order they're started, and added to the test in the order they finish.
+// Returns the test, whose Verdict is Undecided if the games ran out first.
+func RunSPRT(options SPRTOptions, records recordWriter, pairDone func(pair int, opening string, points float64, s *SPRT)) (*SPRT, error) {
+	// The candidate is the first player, who plays red in the first game of each pair
+	options.Red, options.Blue = options.Candidate, options.Baseline
+	s := NewSPRT(options)
+
+	if options.Elo1 <= options.Elo0 {
+		return s, fmt.Errorf("elo1 (%v) must be greater than elo0 (%v)", options.Elo1, options.Elo0)
+	}
+	if options.Alpha <= 0 || options.Alpha >= 1 || options.Beta <= 0 || options.Beta >= 1 {
+		return s, fmt.Errorf("alpha (%v) and beta (%v) must be between 0 and 1", options.Alpha, options.Beta)
+	}
+
+	// Check the settings once, rather than in every game
+	if _, err := newTournamentGame(options.TournamentOptions, 1); err != nil {
+		return s, err
+	}
+	if _, err := randomOpening(options, 1); err != nil {
+		return s, err
+	}
+
+	parallel := options.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	type pairResult struct {
+		gamePair
+		records [2]GameRecord
+	}
+
+	pairs := make(chan gamePair)
+	done := make(chan pairResult)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pair := range pairs {
+				done <- pairResult{gamePair: pair, records: pair.play(options)}
+			}
+		}()
+	}
+
+	feed := func() {
+		for pair := 1; options.Games == 0 || 2*pair <= options.Games; pair++ {
+			opening, _ := randomOpening(options, pair)
+			select {
+			case pairs <- gamePair{number: pair, opening: opening}:
+			case <-stop:
+				return
+			}
+		}
+	}
+
+	go func() {
+		feed()
+		close(pairs)
+		wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	for result := range done {
+		// Pairs still being played when the test ends are left out
+		if s.Verdict() != Undecided {
+			continue
+		}
+
+		points := s.add(result.records)
+		for _, record := range result.records {
+			if err == nil && records != nil {
+				err = records.Write(record)
+			}
+		}
+		pairDone(result.number, result.opening.Transcript(), points, s)
+
+		if s.Verdict() != Undecided {
+			close(stop)
+		}
+	}
+
+	return s, err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// Get a test of the given hypotheses, with 5% error probabilities, whose pairs scored the given counts
+func newTestSPRT(elo0, elo1 float64, pairs [5]int) *SPRT {
+	options := SPRTOptions{Elo0: elo0, Elo1: elo1, Alpha: 0.05, Beta: 0.05}
+	options.Red, options.Blue = "candidate", "baseline"
+	s := NewSPRT(options)
+	s.pairs = pairs
+	return s
+}
+
+func TestSPRTBounds(t *testing.T) {
+	tests := []struct {
+		alpha, beta  float64
+		lower, upper float64
+	}{
+		{0.05, 0.05, -2.944439, 2.944439},
+		{0.05, 0.1, -2.251292, 2.890372},
+		{0.01, 0.01, -4.595120, 4.595120},
+	}
+
+	for _, test := range tests {
+		lower, upper := NewSPRT(SPRTOptions{Alpha: test.alpha, Beta: test.beta}).Bounds()
+		if math.Abs(lower-test.lower) > 1e-6 || math.Abs(upper-test.upper) > 1e-6 {
+			t.Errorf("bounds with alpha %v and beta %v = (%.6f, %.6f), want (%.6f, %.6f)",
+				test.alpha, test.beta, lower, upper, test.lower, test.upper)
+		}
+	}
+}
+
+func TestSPRTLLR(t *testing.T) {
+	tests := []struct {
+		name       string
+		elo0, elo1 float64
+		pairs      [5]int
+		// Sign of the LLR, and the verdict
+		sign    int
+		verdict int
+	}{
+		{"no pairs", 0, 5, [5]int{}, 0, Undecided},
+		{"even pairs between symmetric hypotheses", -5, 5, [5]int{10, 40, 60, 40, 10}, 0, Undecided},
+		{"even pairs", 0, 5, [5]int{10, 40, 60, 40, 10}, -1, Undecided},
+		{"a few won pairs", 0, 5, [5]int{0, 1, 2, 3, 1}, 1, Undecided},
+		{"many won pairs", 0, 5, [5]int{100, 300, 500, 400, 200}, 1, AcceptH1},
+		{"many lost pairs", 0, 5, [5]int{200, 400, 500, 300, 100}, -1, AcceptH0},
+		{"even pairs between negative hypotheses", -10, -5, [5]int{60, 240, 360, 240, 60}, 1, Undecided},
+		{"every pair won", 0, 5, [5]int{0, 0, 0, 0, 50}, 1, AcceptH1},
+		{"every pair lost", 0, 5, [5]int{50, 0, 0, 0, 0}, -1, AcceptH0},
+	}
+
+	for _, test := range tests {
+		s := newTestSPRT(test.elo0, test.elo1, test.pairs)
+		llr := s.LLR()
+		if math.IsNaN(llr) || math.IsInf(llr, 0) {
+			t.Errorf("%v: LLR = %v", test.name, llr)
+			continue
+		}
+
+		sign := 0
+		if llr > 1e-9 {
+			sign = 1
+		} else if llr < -1e-9 {
+			sign = -1
+		}
+		if sign != test.sign {
+			t.Errorf("%v: LLR = %.4f, want its sign to be %v", test.name, llr, test.sign)
+		}
+		if verdict := s.Verdict(); verdict != test.verdict {
+			t.Errorf("%v: verdict = %v with LLR %.4f, want %v", test.name, verdict, llr, test.verdict)
+		}
+	}
+}
+
+func TestSPRTPriorPair(t *testing.T) {
+	// The virtual pair is a whole pair between evenly matched players
+	sum, mean := 0.0, 0.0
+	for i, freq := range priorPair {
+		sum += freq
+		mean += freq * float64(i) / 4
+	}
+	if math.Abs(sum-1) > 1e-12 || math.Abs(mean-0.5) > 1e-12 {
+		t.Errorf("the prior pair sums to %v with a mean score of %v, want 1 and 0.5", sum, mean)
+	}
+
+	// Pairs that all have the same score still have a finite LLR, which only decides after enough of them
+	for i := range priorPair {
+		var pairs [5]int
+		pairs[i] = 3
+		s := newTestSPRT(0, 5, pairs)
+		if llr := s.LLR(); math.IsNaN(llr) || math.IsInf(llr, 0) {
+			t.Errorf("LLR of 3 pairs scoring %v = %v", float64(i)/2, llr)
+		} else if s.Verdict() != Undecided {
+			t.Errorf("3 pairs scoring %v decide the test, with LLR %.4f", float64(i)/2, llr)
+		}
+	}
+
+	// The prior pair weighs less as pairs are played, so more pairs of the same scores give a larger LLR
+	few := newTestSPRT(0, 5, [5]int{0, 0, 0, 2, 2}).LLR()
+	many := newTestSPRT(0, 5, [5]int{0, 0, 0, 20, 20}).LLR()
+	if !(many > 10*few && few > 0) {
+		t.Errorf("LLR of 4 won pairs = %.4f and of 40 = %.4f, want 40 pairs to weigh more than 10 times as much", few, many)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/console"
+	"github.com/M-Balghonaim/Reversi-AI/reversi/engine"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Settings of a tournament between two players, on top of the game settings. The players are the ones
+// given for each color in the game settings, and they swap colors after every game.
+type TournamentOptions struct {
+	Options
+	// Number of games to play
+	Games int
+	// Number of games played at the same time
+	Parallel int
+	// Format of the results: "csv" or "json"
+	Format string
+	// The book loaded from Book, shared by every game rather than loaded for each
+	OpeningBook *engine.Book
+}
+
+// The result of one game of a tournament, as written to the results
+type GameRecord struct {
+	// Number of the game, from 1
+	Game int `json:"game"`
+	// Names of the players of each color, and of the color that moved first
+	Blue  string `json:"blue"`
+	Red   string `json:"red"`
+	First string `json:"first"`
+	// Name of the player who won, or "tie"
+	Winner    string  `json:"winner"`
+	BlueScore int     `json:"blue_score"`
+	RedScore  int     `json:"red_score"`
+	Moves     int     `json:"moves"`
+	Seconds   float64 `json:"seconds"`
+	// Playouts per second of each color, 0 if it ran none
+	BluePlayoutsPerSecond float64 `json:"blue_playouts_per_second"`
+	RedPlayoutsPerSecond  float64 `json:"red_playouts_per_second"`
+	// Positions played, such as "f5d6c3"
+	Transcript string `json:"transcript"`
+}
+
+// Column names of the CSV results
+var csvHeader = []string{"game", "blue", "red", "first", "winner", "blue_score", "red_score", "moves", "seconds", "blue_playouts_per_second", "red_playouts_per_second", "transcript"}
+
+// Get the CSV columns of a game record
+func (g GameRecord) csvRecord() []string {
+	return []string{
+		strconv.Itoa(g.Game), g.Blue, g.Red, g.First, g.Winner,
+		strconv.Itoa(g.BlueScore), strconv.Itoa(g.RedScore), strconv.Itoa(g.Moves),
+		strconv.FormatFloat(g.Seconds, 'f', 3, 64),
+		strconv.FormatFloat(g.BluePlayoutsPerSecond, 'f', 0, 64),
+		strconv.FormatFloat(g.RedPlayoutsPerSecond, 'f', 0, 64),
+		g.Transcript,
+	}
+}
+
+// Writes the results of the games as they finish
+type recordWriter interface {
+	Write(record GameRecord) error
+}
+
+// Writes the results as CSV, with a header line before the first result
+type csvRecordWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// Writes the results as JSON, one object per line
+type jsonRecordWriter struct {
+	encoder *json.Encoder
+}
+
+// Get a writer of the results in the given format
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case "csv":
+		return &csvRecordWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonRecordWriter{encoder: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown results format %q, expected csv or json", format)
+}
+
+// Write the results of a game
+func (c *csvRecordWriter) Write(record GameRecord) error {
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	if err := c.w.Write(record.csvRecord()); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Write the results of a game
+func (j *jsonRecordWriter) Write(record GameRecord) error {
+	return j.encoder.Encode(record)
+}
+
+// Win, loss and tie counts of the first player of a tournament against the second
+type Standings struct {
+	// Names of the two players
+	Player   string `json:"player"`
+	Opponent string `json:"opponent"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+	Ties     int    `json:"ties"`
+}
+
+// Get the number of games played
+func (s Standings) Games() int {
+	return s.Wins + s.Losses + s.Ties
+}
+
+// Get the fraction of the points won by the player, where a win is worth one point and a tie half a point,
+// and the bounds of its 95% confidence interval. The Wilson score interval is used, since unlike the
+// normal approximation it stays meaningful when one player wins every game.
+func (s Standings) WinRate() (float64, float64, float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, 0, 1
+	}
+
+	rate := (float64(s.Wins) + 0.5*float64(s.Ties)) / n
+
+	const z = 1.96
+	center := (rate + z*z/(2*n)) / (1 + z*z/n)
+	halfWidth := z / (1 + z*z/n) * math.Sqrt(rate*(1-rate)/n+z*z/(4*n*n))
+	return rate, math.Max(0, center-halfWidth), math.Min(1, center+halfWidth)
+}
+
+// Add the result of a game from the point of view of the player
+func (s *Standings) add(record GameRecord) {
+	if record.Winner == s.Player {
+		s.Wins += 1
+	} else if record.Winner == s.Opponent {
+		s.Losses += 1
+	} else {
+		s.Ties += 1
+	}
+}
+
+// Get the summary of the standings, such as
+// "mcts vs flat: 60 wins, 35 losses, 5 ties, win rate 62.5% (95% confidence interval 52.7% to 71.4%)"
+func (s Standings) String() string {
+	rate, low, high := s.WinRate()
+	return fmt.Sprintf("%v vs %v: %v wins, %v losses, %v ties, win rate %.1f%% (95%% confidence interval %.1f%% to %.1f%%)",
+		s.Player, s.Opponent, s.Wins, s.Losses, s.Ties, 100*rate, 100*low, 100*high)
+}
+
+// Get the names the two players are known by in the results, which are their player names unless both
+// players have the same one
+func playerNames(options Options) (string, string) {
+	if options.Red == options.Blue {
+		return options.Red + "-1", options.Blue + "-2"
+	}
+	return options.Red, options.Blue
+}
+
+// Get the number of search workers of each game, so that the games played at the same time share the
+// CPU cores rather than each using all of them
+func (options TournamentOptions) workers() int {
+	if options.Parallel <= 1 {
+		// One per CPU core
+		return 0
+	}
+	if workers := runtime.NumCPU() / options.Parallel; workers > 1 {
+		return workers
+	}
+	return 1
+}
+
+// Lower case names of the colors, as written to the results
+var colorNames = map[int]string{engine.Blue: "blue", engine.Red: "red"}
+
+// A game of a tournament, ready to be played
+type tournamentGame struct {
+	*engine.Reversi
+	number  int
+	players map[int]engine.Player
+	// Names of the players of each color
+	names map[int]string
+}
+
+// Set up the given game of the tournament: in odd games the first player plays red, in even games blue
+func newTournamentGame(options TournamentOptions, game int) (*tournamentGame, error) {
+	first, err := console.ParseColor(options.First)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players are created for each game, since the random ones can't be shared between games. Each game
+	// has its own seed, so that it plays the same way whichever games are played at the same time.
+	config := engine.PlayerConfig{EndgameEmpties: options.EndgameEmpties, Playouts: options.Playouts, Workers: options.workers(), Book: options.OpeningBook}
+	seed := engine.DeriveSeed(options.Seed, int64(game))
+	player, opponent := playerNames(options.Options)
+	names := map[int]string{engine.Red: player, engine.Blue: opponent}
+	kinds := map[int]string{engine.Red: options.Red, engine.Blue: options.Blue}
+	if game%2 == 0 {
+		names[engine.Red], names[engine.Blue] = names[engine.Blue], names[engine.Red]
+		kinds[engine.Red], kinds[engine.Blue] = kinds[engine.Blue], kinds[engine.Red]
+	}
+
+	players := make(map[int]engine.Player)
+	for color, kind := range kinds {
+		config.Seed = engine.DeriveSeed(seed, int64(color))
+		if players[color], err = engine.NewPlayer(kind, config); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := engine.NewSize(options.Size, first)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tournamentGame{Reversi: r, number: game, players: players, names: names}, nil
+}
+
+// Play the game out and return its result
+func (t *tournamentGame) play(timeControl engine.TimeControl) GameRecord {
+	names := t.names
+	first := t.Turn()
+	result := engine.PlayGame(t.Reversi, t.players, engine.NewClock(timeControl))
+
+	record := GameRecord{
+		Game:                  t.number,
+		Blue:                  names[engine.Blue],
+		Red:                   names[engine.Red],
+		First:                 colorNames[first],
+		Winner:                "tie",
+		BlueScore:             result.BlueScore,
+		RedScore:              result.RedScore,
+		Moves:                 result.Moves,
+		Seconds:               result.Elapsed.Seconds(),
+		BluePlayoutsPerSecond: result.PlayOutsPerSecond(engine.Blue),
+		RedPlayoutsPerSecond:  result.PlayOutsPerSecond(engine.Red),
+		Transcript:            t.Transcript(),
+	}
+	if result.Winner != engine.Tie {
+		record.Winner = names[result.Winner]
+	}
+
+	return record
+}
+
+// Play the games of a tournament, writing the result of each game to records as it finishes, unless
+// records is nil, and return the standings of the first player
+func RunTournament(options TournamentOptions, records recordWriter) (Standings, error) {
+	player, opponent := playerNames(options.Options)
+	standings := Standings{Player: player, Opponent: opponent}
+
+	// Check the settings once, rather than in every game
+	if _, err := newTournamentGame(options, 1); err != nil {
+		return standings, err
+	}
+
+	parallel := options.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	games := make(chan int)
+	done := make(chan GameRecord)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for game := range games {
+				// The settings were checked before starting
+				t, _ := newTournamentGame(options, game)
+				done <- t.play(options.TimeControl)
+			}
+		}()
+	}
+
+	go func() {
+		for game := 1; game <= options.Games; game++ {
+			games <- game
+		}
+		close(games)
+		wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	for record := range done {
+		standings.add(record)
+		if err == nil && records != nil {
+			err = records.Write(record)
+		}
+	}
+
+	return standings, err
+}